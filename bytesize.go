@@ -0,0 +1,154 @@
+package configlib
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a number of bytes that is parsed from human-readable sizes with
+// SI (e.g. "1.5GB") or IEC (e.g. "64MiB") suffixes, or from a plain number.
+// Integer fields accept the same syntax with the `unit:"bytes"` tag.
+type ByteSize uint64
+
+// Common byte sizes
+const (
+	Byte ByteSize = 1
+
+	KB ByteSize = 1000 * Byte
+	MB ByteSize = 1000 * KB
+	GB ByteSize = 1000 * MB
+	TB ByteSize = 1000 * GB
+	PB ByteSize = 1000 * TB
+	EB ByteSize = 1000 * PB
+
+	KiB ByteSize = 1 << 10
+	MiB ByteSize = 1 << 20
+	GiB ByteSize = 1 << 30
+	TiB ByteSize = 1 << 40
+	PiB ByteSize = 1 << 50
+	EiB ByteSize = 1 << 60
+)
+
+// unitBytes is the unit tag value for integer fields holding byte sizes
+const unitBytes = "bytes"
+
+var byteSizeType = reflect.TypeOf(ByteSize(0))
+
+type byteUnit struct {
+	suffix string
+	size   ByteSize
+}
+
+// byteUnits lists suffixes from largest to smallest within each family
+var (
+	iecUnits = []byteUnit{{"EiB", EiB}, {"PiB", PiB}, {"TiB", TiB}, {"GiB", GiB}, {"MiB", MiB}, {"KiB", KiB}}
+	siUnits  = []byteUnit{{"EB", EB}, {"PB", PB}, {"TB", TB}, {"GB", GB}, {"MB", MB}, {"KB", KB}}
+)
+
+// byteSuffixes maps lowercase suffixes to their sizes. A bare K, M, G, ...
+// is decimal, while Ki, Mi, Gi, ... are binary.
+var byteSuffixes = map[string]ByteSize{
+	"": Byte, "b": Byte,
+	"k": KB, "kb": KB, "ki": KiB, "kib": KiB,
+	"m": MB, "mb": MB, "mi": MiB, "mib": MiB,
+	"g": GB, "gb": GB, "gi": GiB, "gib": GiB,
+	"t": TB, "tb": TB, "ti": TiB, "tib": TiB,
+	"p": PB, "pb": PB, "pi": PiB, "pib": PiB,
+	"e": EB, "eb": EB, "ei": EiB, "eib": EiB,
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := parseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// String formats b with the unit giving the shortest exact representation,
+// e.g. "64MiB" or "1.5GB", falling back to a number of bytes
+func (b ByteSize) String() string {
+	best := ""
+	for _, units := range [][]byteUnit{iecUnits, siUnits} {
+		for _, unit := range units {
+			if b < unit.size {
+				continue
+			}
+			// Allow up to two decimal places, e.g. 1.5GB or 2.25MiB
+			whole, frac := uint64(b/unit.size), uint64(b%unit.size)
+			hi, lo := bits.Mul64(frac, 100)
+			cents, rem := bits.Div64(hi, lo, uint64(unit.size))
+			if rem != 0 {
+				continue
+			}
+			s := strconv.FormatUint(whole, 10)
+			if cents != 0 {
+				s += "." + strings.TrimRight(fmt.Sprintf("%02d", cents), "0")
+			}
+			if s += unit.suffix; best == "" || len(s) < len(best) {
+				best = s
+			}
+			break
+		}
+	}
+	if best == "" {
+		best = strconv.FormatUint(uint64(b), 10) + "B"
+	}
+	return best
+}
+
+// parseByteSize parses a number with an optional SI or IEC suffix
+func parseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	end := strings.LastIndexAny(s, "0123456789.") + 1
+	num, suffix := s[:end], strings.TrimSpace(s[end:])
+
+	unit, ok := byteSuffixes[strings.ToLower(suffix)]
+	if !ok || num == "" {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	// Whole numbers are multiplied exactly, fractions through float64
+	if n, err := strconv.ParseUint(num, 10, 64); err == nil {
+		if n > math.MaxUint64/uint64(unit) {
+			return 0, fmt.Errorf("byte size %q out of range", s)
+		}
+		return ByteSize(n) * unit, nil
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	size := f * float64(unit)
+	if size >= math.MaxUint64 {
+		return 0, fmt.Errorf("byte size %q out of range", s)
+	}
+	return ByteSize(size), nil
+}
+
+// validUnit reports whether the unit tag of field is supported by its type:
+// integers, or slices and maps of integers, that are not parsed by a decoder
+func (p *Parser) validUnit(field Field) bool {
+	if field.Unit == "" {
+		return true
+	}
+	t := p.valueType(field.Type)
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		t = p.valueType(t.Elem())
+	}
+	if field.Unit != unitBytes || p.isLeafType(t) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
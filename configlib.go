@@ -1,38 +1,116 @@
 package configlib
 
 import (
+	"encoding"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// ErrHelp is returned by Parse when --help or -h was given and the help
+// message has been printed
+var ErrHelp = errors.New("help requested")
+
+// Field describes a configuration field collected from the config struct
+type Field struct {
+	EnvName        string
+	CliName        string
+	CliNames       []string // All CLI names (including shorthand)
+	DefaultVal     string
+	Required       bool
+	RequiredIf     string // Required if the fields (by FieldPath) have the values, e.g. "Mode=tls"
+	RequiredUnless string // Required unless the fields (by FieldPath) have the values
+	RequiredWith   string // Required if any of the fields (by FieldPath) has a value
+	Exclusive      string // Groups in which at most one field may have a value
+	AtLeastOne     string // Groups in which at least one field must have a value
+	Secret         bool   // Whether the value is redacted from Provenance and Explain
+	Description    string
+	Repeat         string   // How repeated flags combine for slices: split, append or replace
+	Sep            string   // Separator between slice elements and map entries
+	Layout         string   // Layout for time.Time values, RFC 3339 if empty
+	Unit           string   // Unit of integer values; "bytes" accepts sizes like 64MiB
+	OneOf          []string // Allowed values; for slices and maps, of each element or value
+	Min, Max       string   // Bounds on numbers; for slices and maps, on each element or value
+	MinLen         string   // Minimum length of strings, or entries in slices and maps
+	MaxLen         string   // Maximum length of strings, or entries in slices and maps
+	Pattern        string   // Regular expression that whole strings must match
+	FieldPath      string
+	Tags           []reflect.StructTag // Struct tags along FieldPath, outermost first
+	Type           reflect.Type
+}
+
 type fieldInfo struct {
-	EnvName     string
-	CliName     string
-	CliNames    []string // All CLI names (including shorthand)
-	DefaultVal  string
-	Required    bool
-	Description string
-	FieldPath   string
-	Value       reflect.Value
-	Type        reflect.Type
+	Field
+	Value  reflect.Value
+	allocs []pointerAlloc // Nil struct pointers to fill in when this field is set
+
+	// Validation rules parsed from the tags
+	oneOf          []reflect.Value
+	min, max       reflect.Value // Invalid if unset
+	minLen, maxLen int           // -1 if unset
+	pattern        *regexp.Regexp
+
+	// Conditional requirements resolved once all fields are collected
+	requiredIf, requiredUnless, requiredWith []condition
+}
+
+// pointerAlloc is a nil pointer-to-struct field and the struct allocated for it
+type pointerAlloc struct {
+	field reflect.Value
+	ptr   reflect.Value
+}
+
+// leftNil reports whether any of the struct pointers in allocs is still nil
+// once values are applied
+func leftNil(allocs []pointerAlloc) bool {
+	for _, alloc := range allocs {
+		if alloc.field.IsNil() {
+			return true
+		}
+	}
+	return false
+}
+
+// structInfo is a struct walked by walkStruct, validated once values are applied
+type structInfo struct {
+	path   string
+	tags   []reflect.StructTag // Struct tags along path, outermost first
+	value  reflect.Value
+	allocs []pointerAlloc // Nil struct pointers leading to the struct
 }
 
 type Parser struct {
 	fields     []fieldInfo
+	structs    []structInfo
 	flagSet    *flag.FlagSet
-	flagValues map[string]string
+	flagValues map[string][]string // Values of each flag occurrence, in order
 	showHelp   bool
 	boolFlags  map[string]*bool // Track boolean flags
+	flagErr    *FieldError      // Invalid value rejected by a flag handler
 
 	// Options
 	disableAutoEnv  bool
 	disableAutoFlag bool
 	envPrefix       string
+	exitOnHelp      bool
+	configPaths     []string
+	configFiles     []configFile
+	fileFormats     map[string]fileFormat
+	dotEnvPaths     []string
+	dotEnv          map[string]string
+	sources         []Source
+	provenance      []Provenance
+	lookupEnv       func(string) (string, bool)
+	environ         func() []string
+	decoders        map[reflect.Type]func(string) (any, error)
 }
 
 // Option is a functional option for configuring a Parser
@@ -41,10 +119,15 @@ type Option func(*Parser)
 // NewParser creates a new parser with the given options
 func NewParser(opts ...Option) *Parser {
 	p := &Parser{
-		flagSet:    flag.NewFlagSet("config", flag.ContinueOnError),
-		fields:     make([]fieldInfo, 0),
-		flagValues: make(map[string]string),
-		boolFlags:  make(map[string]*bool),
+		flagSet:     flag.NewFlagSet("config", flag.ContinueOnError),
+		fields:      make([]fieldInfo, 0),
+		flagValues:  make(map[string][]string),
+		boolFlags:   make(map[string]*bool),
+		lookupEnv:   os.LookupEnv,
+		environ:     os.Environ,
+		fileFormats: make(map[string]fileFormat),
+		dotEnv:      make(map[string]string),
+		decoders:    make(map[reflect.Type]func(string) (any, error)),
 	}
 
 	// Apply options
@@ -52,6 +135,8 @@ func NewParser(opts ...Option) *Parser {
 		opt(p)
 	}
 
+	p.flagSet.SetOutput(flagOutput{p})
+
 	// Add help flag
 	p.flagSet.BoolVar(&p.showHelp, "help", false, "Show help message")
 	p.flagSet.BoolVar(&p.showHelp, "h", false, "Show help message")
@@ -80,9 +165,59 @@ func WithEnvPrefix(prefix string) Option {
 	}
 }
 
+// WithExitOnHelp makes Parse exit the process with status 0 after printing
+// help instead of returning ErrHelp
+func WithExitOnHelp() Option {
+	return func(p *Parser) {
+		p.exitOnHelp = true
+	}
+}
+
+// WithEnvLookup sets the function used to look up environment variables.
+// It defaults to os.LookupEnv. Since a lookup function cannot list variables,
+// map fields are only read from their own variable (e.g. LABELS), not from
+// per-key variables (e.g. LABELS_TEAM); use WithEnviron for those.
+func WithEnvLookup(lookup func(string) (string, bool)) Option {
+	return func(p *Parser) {
+		p.lookupEnv = lookup
+		p.environ = nil
+	}
+}
+
+// WithEnviron sets the environment to a list of "KEY=value" entries, in the
+// form returned by os.Environ, instead of the process environment
+func WithEnviron(environ []string) Option {
+	return func(p *Parser) {
+		vars := make(map[string]string, len(environ))
+		for _, entry := range environ {
+			if key, val, ok := strings.Cut(entry, "="); ok {
+				vars[key] = val
+			}
+		}
+		p.lookupEnv = func(key string) (string, bool) {
+			val, ok := vars[key]
+			return val, ok
+		}
+		p.environ = func() []string {
+			return environ
+		}
+	}
+}
+
+// Parse parses configuration into config from os.Args[1:] and the process environment
 func (p *Parser) Parse(config any) error {
+	return p.ParseArgs(config, os.Args[1:])
+}
+
+// ParseArgs parses configuration into config from the given CLI arguments
+// (without the program name) and the parser's environment lookup
+func (p *Parser) ParseArgs(config any, args []string) error {
 	// Step 1: Walk the struct and collect all fields with their metadata
-	err := p.walkStruct(reflect.ValueOf(config).Elem(), "")
+	err := p.walkStruct(reflect.ValueOf(config).Elem(), "", nil, nil)
+	if err != nil {
+		return err
+	}
+	err = p.resolveRequirements()
 	if err != nil {
 		return err
 	}
@@ -91,15 +226,21 @@ func (p *Parser) Parse(config any) error {
 	p.registerFlags()
 
 	// Step 3: Parse CLI arguments
-	err = p.flagSet.Parse(os.Args[1:])
+	err = p.flagSet.Parse(args)
 	if err != nil {
+		if p.flagErr != nil {
+			return &flagError{msg: p.redactFlagMessage(err.Error()), err: p.flagErr}
+		}
 		return err
 	}
 
 	// Check if help was requested
 	if p.showHelp {
 		p.PrintHelp()
-		os.Exit(0)
+		if p.exitOnHelp {
+			os.Exit(0)
+		}
+		return ErrHelp
 	}
 
 	// Process boolean flags that were set
@@ -109,7 +250,7 @@ func (p *Parser) Parse(config any) error {
 			for _, field := range p.fields {
 				for _, name := range field.CliNames {
 					if name == f.Name {
-						p.flagValues[field.CliName] = strconv.FormatBool(*boolPtr)
+						p.flagValues[field.CliName] = []string{strconv.FormatBool(*boolPtr)}
 						break
 					}
 				}
@@ -117,11 +258,21 @@ func (p *Parser) Parse(config any) error {
 		}
 	})
 
-	// Step 4: Apply values with precedence: CLI > Env > Default
+	// Step 4: Load dotenv and config files
+	err = p.loadDotEnv()
+	if err != nil {
+		return err
+	}
+	err = p.loadConfigFiles()
+	if err != nil {
+		return err
+	}
+
+	// Step 5: Apply values with precedence: CLI > Env > Dotenv > Sources > File > Default
 	return p.applyValues()
 }
 
-func (p *Parser) walkStruct(val reflect.Value, pathPrefix string) error {
+func (p *Parser) walkStruct(val reflect.Value, pathPrefix string, tags []reflect.StructTag, allocs []pointerAlloc) error {
 	typ := val.Type()
 
 	for i := 0; i < val.NumField(); i++ {
@@ -138,10 +289,29 @@ func (p *Parser) walkStruct(val reflect.Value, pathPrefix string) error {
 		if pathPrefix != "" {
 			fieldPath = pathPrefix + "." + fieldType.Name
 		}
+		fieldTags := append(tags[:len(tags):len(tags)], fieldType.Tag)
+
+		// Handle nested structs recursively, unless they are parsed as a
+		// single value (e.g. time.Time)
+		if field.Kind() == reflect.Struct && !p.isLeafType(field.Type()) {
+			err := p.walkStruct(field, fieldPath, fieldTags, allocs)
+			if err != nil {
+				return err
+			}
+			continue
+		}
 
-		// Handle nested structs recursively
-		if field.Kind() == reflect.Struct {
-			err := p.walkStruct(field, fieldPath)
+		// Handle pointers to structs, allocating nil ones only once one of
+		// their fields is set
+		if field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct &&
+			!p.isLeafType(field.Type()) && !p.isLeafType(field.Type().Elem()) {
+			structVal := field
+			fieldAllocs := allocs
+			if field.IsNil() {
+				structVal = reflect.New(field.Type().Elem())
+				fieldAllocs = append(allocs[:len(allocs):len(allocs)], pointerAlloc{field: field, ptr: structVal})
+			}
+			err := p.walkStruct(structVal.Elem(), fieldPath, fieldTags, fieldAllocs)
 			if err != nil {
 				return err
 			}
@@ -150,20 +320,41 @@ func (p *Parser) walkStruct(val reflect.Value, pathPrefix string) error {
 
 		// Parse tags for this field
 		info := p.parseFieldTags(fieldType, fieldPath, field)
+		info.Tags = fieldTags
+		info.allocs = allocs
+		switch info.Repeat {
+		case "", repeatSplit, repeatAppend, repeatReplace:
+		default:
+			return fmt.Errorf("invalid repeat tag %q on field %s: must be %s, %s or %s",
+				info.Repeat, fieldPath, repeatSplit, repeatAppend, repeatReplace)
+		}
+		if !p.validUnit(info.Field) {
+			return fmt.Errorf("invalid unit tag %q on field %s: only %s is supported, on integer fields",
+				info.Unit, fieldPath, unitBytes)
+		}
+		if err := p.parseRules(&info); err != nil {
+			return err
+		}
 		// Only add fields that have at least one way to be configured
-		if info.EnvName != "" || info.CliName != "" || info.DefaultVal != "" {
+		if info.EnvName != "" || info.CliName != "" || info.DefaultVal != "" ||
+			len(p.configPaths) > 0 || len(p.sources) > 0 {
 			p.fields = append(p.fields, info)
 		}
 	}
 
+	// Record structs after their nested ones so they are validated bottom-up
+	p.structs = append(p.structs, structInfo{path: pathPrefix, tags: tags, value: val, allocs: allocs})
+
 	return nil
 }
 
 func (p *Parser) parseFieldTags(field reflect.StructField, path string, value reflect.Value) fieldInfo {
 	info := fieldInfo{
-		FieldPath: path,
-		Value:     value,
-		Type:      field.Type,
+		Field: Field{
+			FieldPath: path,
+			Type:      field.Type,
+		},
+		Value: value,
 	}
 
 	// Parse env tag
@@ -200,7 +391,30 @@ func (p *Parser) parseFieldTags(field reflect.StructField, path string, value re
 	// Parse other tags
 	info.DefaultVal = field.Tag.Get("default")
 	info.Required = field.Tag.Get("required") == "true"
+	info.RequiredIf = field.Tag.Get("required_if")
+	info.RequiredUnless = field.Tag.Get("required_unless")
+	info.RequiredWith = field.Tag.Get("required_with")
+	info.Exclusive = field.Tag.Get("exclusive")
+	info.AtLeastOne = field.Tag.Get("atleastone")
+	info.Secret = field.Tag.Get("secret") == "true"
 	info.Description = field.Tag.Get("desc")
+	info.Repeat = field.Tag.Get("repeat")
+	info.Sep = field.Tag.Get("sep")
+	if info.Sep == "" {
+		info.Sep = ","
+	}
+	info.Layout = timeLayout(field.Tag.Get("layout"))
+	info.Unit = field.Tag.Get("unit")
+	info.Min = field.Tag.Get("min")
+	info.Max = field.Tag.Get("max")
+	info.MinLen = field.Tag.Get("minlen")
+	info.MaxLen = field.Tag.Get("maxlen")
+	info.Pattern = field.Tag.Get("pattern")
+	if oneof := field.Tag.Get("oneof"); oneof != "" {
+		for _, allowed := range strings.Split(oneof, ",") {
+			info.OneOf = append(info.OneOf, strings.TrimSpace(allowed))
+		}
+	}
 
 	return info
 }
@@ -214,30 +428,44 @@ func (p *Parser) registerFlags() {
 			continue
 		}
 
+		// Pointer fields are registered by the type they point to
+		typ := p.valueType(field.Type)
+
 		// Register all flag names for this field
 		for _, flagName := range field.CliNames {
-			// Check for time.Duration first (special case)
-			if field.Type.String() == "time.Duration" {
-				p.flagSet.Func(flagName, field.Description, p.createDurationHandler(field.CliName))
+			// Types with a decoder or implementing encoding.TextUnmarshaler
+			// or flag.Value
+			if p.isLeafType(typ) {
+				p.flagSet.Func(flagName, field.Description, p.createValueHandler(field.Field, typ))
 				continue
 			}
 
-			switch field.Type.Kind() {
+			switch typ.Kind() {
 			case reflect.String:
 				p.flagSet.Func(flagName, field.Description, p.createStringHandler(field.CliName))
 			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				p.flagSet.Func(flagName, field.Description, p.createIntHandler(field.CliName))
+				if field.Unit != "" {
+					p.flagSet.Func(flagName, field.Description, p.createValueHandler(field.Field, typ))
+					continue
+				}
+				p.flagSet.Func(flagName, field.Description, p.createIntHandler(field.Field))
 			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				p.flagSet.Func(flagName, field.Description, p.createUintHandler(field.CliName))
+				if field.Unit != "" {
+					p.flagSet.Func(flagName, field.Description, p.createValueHandler(field.Field, typ))
+					continue
+				}
+				p.flagSet.Func(flagName, field.Description, p.createUintHandler(field.Field))
 			case reflect.Float32, reflect.Float64:
-				p.flagSet.Func(flagName, field.Description, p.createFloatHandler(field.CliName))
+				p.flagSet.Func(flagName, field.Description, p.createFloatHandler(field.Field))
 			case reflect.Bool:
 				// Use BoolVar for boolean flags so they don't require a value
 				boolPtr := new(bool)
 				p.flagSet.BoolVar(boolPtr, flagName, false, field.Description)
 				p.boolFlags[flagName] = boolPtr
 			case reflect.Slice:
-				p.flagSet.Func(flagName, field.Description, p.createSliceHandler(field.CliName))
+				p.flagSet.Func(flagName, field.Description, p.createSliceHandler(field.Field, typ))
+			case reflect.Map:
+				p.flagSet.Func(flagName, field.Description, p.createMapHandler(field.Field))
 			}
 		}
 	}
@@ -250,17 +478,17 @@ func (p *Parser) registerFlags() {
 
 func (p *Parser) createStringHandler(flagName string) func(string) error {
 	return func(s string) error {
-		p.flagValues[flagName] = s
+		p.flagValues[flagName] = append(p.flagValues[flagName], s)
 		return nil
 	}
 }
 
-func (p *Parser) createIntHandler(flagName string) func(string) error {
+func (p *Parser) createIntHandler(field Field) func(string) error {
 	return func(s string) error {
 		if _, err := strconv.Atoi(s); err != nil {
-			return fmt.Errorf("invalid integer value: %s", s)
+			return p.invalidFlag(field, s, fmt.Errorf("invalid integer value: %s", s))
 		}
-		p.flagValues[flagName] = s
+		p.flagValues[field.CliName] = append(p.flagValues[field.CliName], s)
 		return nil
 	}
 }
@@ -269,165 +497,471 @@ func (p *Parser) createBoolHandler(flagName string) func(string) error {
 	return func(s string) error {
 		// For boolean flags, if no value is provided, assume true
 		if s == "" {
-			p.flagValues[flagName] = "true"
+			p.flagValues[flagName] = append(p.flagValues[flagName], "true")
 			return nil
 		}
 		if _, err := strconv.ParseBool(s); err != nil {
 			return fmt.Errorf("invalid boolean value: %s", s)
 		}
-		p.flagValues[flagName] = s
+		p.flagValues[flagName] = append(p.flagValues[flagName], s)
 		return nil
 	}
 }
 
-func (p *Parser) createFloatHandler(flagName string) func(string) error {
+func (p *Parser) createFloatHandler(field Field) func(string) error {
 	return func(s string) error {
 		if _, err := strconv.ParseFloat(s, 64); err != nil {
-			return fmt.Errorf("invalid float value: %s", s)
+			return p.invalidFlag(field, s, fmt.Errorf("invalid float value: %s", s))
 		}
-		p.flagValues[flagName] = s
+		p.flagValues[field.CliName] = append(p.flagValues[field.CliName], s)
 		return nil
 	}
 }
 
-func (p *Parser) createUintHandler(flagName string) func(string) error {
+func (p *Parser) createUintHandler(field Field) func(string) error {
 	return func(s string) error {
 		if _, err := strconv.ParseUint(s, 10, 64); err != nil {
-			return fmt.Errorf("invalid unsigned integer value: %s", s)
+			return p.invalidFlag(field, s, fmt.Errorf("invalid unsigned integer value: %s", s))
+		}
+		p.flagValues[field.CliName] = append(p.flagValues[field.CliName], s)
+		return nil
+	}
+}
+
+func (p *Parser) createValueHandler(field Field, typ reflect.Type) func(string) error {
+	return func(s string) error {
+		if err := p.setValue(reflect.New(typ).Elem(), s, field); err != nil {
+			return p.invalidFlag(field, s, err)
 		}
-		p.flagValues[flagName] = s
+		p.flagValues[field.CliName] = append(p.flagValues[field.CliName], s)
 		return nil
 	}
 }
 
-func (p *Parser) createDurationHandler(flagName string) func(string) error {
+func (p *Parser) createSliceHandler(field Field, typ reflect.Type) func(string) error {
 	return func(s string) error {
-		if _, err := time.ParseDuration(s); err != nil {
-			return fmt.Errorf("invalid duration value: %s", s)
+		// In append mode each occurrence is a single element
+		target := reflect.New(typ).Elem()
+		if field.Repeat == repeatAppend {
+			target = reflect.New(typ.Elem()).Elem()
+		}
+		if err := p.setValue(target, s, field); err != nil {
+			return p.invalidFlag(field, s, err)
 		}
-		p.flagValues[flagName] = s
+		p.flagValues[field.CliName] = append(p.flagValues[field.CliName], s)
 		return nil
 	}
 }
 
-func (p *Parser) createSliceHandler(flagName string) func(string) error {
+func (p *Parser) createMapHandler(field Field) func(string) error {
 	return func(s string) error {
-		p.flagValues[flagName] = s
+		if _, err := splitMapEntries(s, field.Sep); err != nil {
+			return p.invalidFlag(field, s, err)
+		}
+		p.flagValues[field.CliName] = append(p.flagValues[field.CliName], s)
 		return nil
 	}
 }
 
+// invalidFlag records a FieldError for an invalid flag value, which ParseArgs
+// exposes behind the flag package's error, and returns err for its message
+func (p *Parser) invalidFlag(field Field, value string, err error) error {
+	p.flagErr = newFieldError(field, flagSource{}.Name(), value, err)
+	return p.flagErr.Err
+}
+
+// redactFlagMessage hides the value of a secret field from a message of the
+// flag package about the invalid flag value recorded by invalidFlag
+func (p *Parser) redactFlagMessage(msg string) string {
+	if p.flagErr != nil {
+		if re, ok := p.flagErr.Err.(*redactedError); ok {
+			return re.redact(msg)
+		}
+	}
+	return msg
+}
+
+// flagOutput is where the flag package prints errors, redacting secret values
+type flagOutput struct {
+	p *Parser
+}
+
+func (o flagOutput) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(os.Stderr, o.p.redactFlagMessage(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
 func (p *Parser) applyValues() error {
-	var missingFields []string
+	var missingFields []MissingField
+	var invalidFields []error
+	applied := make([]bool, len(p.fields))
+	explicit := make([]bool, len(p.fields)) // Applied from a source other than the default tag
+	chain := p.sourceChain()
 
-	for _, field := range p.fields {
+	for i, field := range p.fields {
 		var finalValue string
 		var hasValue bool
 
-		// Priority 1: CLI flags (only if non-empty and CLI name exists)
-		if field.CliName != "" {
-			if val, exists := p.flagValues[field.CliName]; exists && val != "" {
-				finalValue = val
-				hasValue = true
-			}
-		}
+		var finalElems []string
 
-		// Priority 2: Environment variables (only if non-empty and env name exists)
-		if !hasValue && field.EnvName != "" {
-			if envVal := os.Getenv(field.EnvName); envVal != "" {
-				finalValue = envVal
-				hasValue = true
+		// Take the value from the first source that has one, recording
+		// lower-priority sources it shadows
+		prov := Provenance{FieldPath: field.FieldPath}
+		for _, src := range chain {
+			val, ok := src.Lookup(field.Field)
+			if !ok || val == "" {
+				continue
 			}
-		}
-
-		// Priority 3: Default values (only if non-empty)
-		if !hasValue && field.DefaultVal != "" {
-			finalValue = field.DefaultVal
-			hasValue = true
-		}
-
-		// Check required fields
-		if field.Required && !hasValue {
-			var sources []string
-			if field.EnvName != "" {
-				sources = append(sources, fmt.Sprintf("env: %s", field.EnvName))
+			if hasValue {
+				prov.Shadowed = append(prov.Shadowed, src.Name())
+				continue
 			}
-			if field.CliName != "" {
-				sources = append(sources, fmt.Sprintf("flag: --%s", field.CliName))
+			finalValue = val
+			hasValue = true
+			prov.Source = src.Name()
+			prov.Value = val
+
+			// Some sources supply slice elements directly, unless the slice
+			// type is converted as a whole, like net.IP
+			typ := p.valueType(field.Type)
+			if ls, ok := src.(listSource); ok && typ.Kind() == reflect.Slice && !p.isLeafType(typ) {
+				finalElems, _ = ls.lookupList(field.Field)
 			}
-			missingFields = append(missingFields, fmt.Sprintf("%s (%s)",
-				field.FieldPath, strings.Join(sources, ", ")))
 		}
+		if field.Secret && hasValue {
+			prov.Value = redacted
+		}
+		p.provenance = append(p.provenance, prov)
+		applied[i] = hasValue
+		explicit[i] = hasValue && prov.Source != defaultSource{}.Name()
 
 		// Set the value if we have one
 		if hasValue {
-			err := p.setFieldValue(field, finalValue)
+			var err error
+			if finalElems != nil {
+				err = p.setFieldElems(field, finalElems)
+			} else {
+				err = p.setFieldValue(field, finalValue)
+			}
 			if err != nil {
-				return fmt.Errorf("error setting field %s: %v", field.FieldPath, err)
+				return newFieldError(field.Field, prov.Source, finalValue, err)
 			}
+			invalidFields = append(invalidFields, p.validateField(field, finalValue, finalElems)...)
+		}
+	}
+
+	// Report config file keys that do not map to any field
+	if err := p.checkUnknownKeys(); err != nil {
+		return err
+	}
+
+	// Check required fields once all values are applied, since conditional
+	// requirements depend on other fields. Fields in structs behind pointers
+	// that were left nil are not configured, so they are not required.
+	for i, field := range p.fields {
+		if leftNil(field.allocs) {
+			continue
 		}
+		required, condition := p.isRequired(field, applied, explicit)
+		if !required || applied[i] {
+			continue
+		}
+		missingFields = append(missingFields, MissingField{
+			FieldPath: field.FieldPath,
+			EnvName:   field.EnvName,
+			CliName:   field.CliName,
+			Condition: condition,
+		})
 	}
 
-	// If there are missing required fields, return an error with all of them
+	// If there are missing required fields, invalid values or failed
+	// validations, return an error with all of them
+	var errs []error
 	if len(missingFields) > 0 {
-		return fmt.Errorf("missing required fields:\n  - %s", strings.Join(missingFields, "\n  - "))
+		errs = append(errs, &MissingFieldsError{Fields: missingFields})
+	}
+	errs = append(errs, invalidFields...)
+	errs = append(errs, p.checkGroups(explicit)...)
+	errs = append(errs, p.validateStructs()...)
+	if len(errs) > 0 {
+		return &MultiError{Errors: errs}
 	}
 
 	return nil
 }
 
 func (p *Parser) setFieldValue(field fieldInfo, value string) error {
-	// Handle time.Duration first (special case)
-	if field.Type.String() == "time.Duration" {
-		duration, err := time.ParseDuration(value)
+	return p.assignField(field, func(target reflect.Value) error {
+		return p.setValue(target, value, field.Field)
+	})
+}
+
+// setFieldElems sets a slice field from already split elements
+func (p *Parser) setFieldElems(field fieldInfo, elems []string) error {
+	return p.assignField(field, func(target reflect.Value) error {
+		return p.setSlice(target, elems, field.Field)
+	})
+}
+
+// assignField stores a value into field using set, allocating pointers as needed
+func (p *Parser) assignField(field fieldInfo, set func(reflect.Value) error) error {
+	// Pointer fields get a newly allocated value, leaving them nil when unset
+	target := field.Value
+	typ := p.valueType(field.Type)
+	if typ != field.Type {
+		target = reflect.New(typ).Elem()
+	}
+
+	err := set(target)
+	if err != nil {
+		return err
+	}
+
+	if typ != field.Type {
+		field.Value.Set(target.Addr())
+	}
+
+	// Link any nil struct pointers leading to this field
+	for _, alloc := range field.allocs {
+		if alloc.field.IsNil() {
+			alloc.field.Set(alloc.ptr)
+		}
+	}
+
+	return nil
+}
+
+// setValue converts value to the type of target and stores it. Slices and
+// maps are split using field's separator.
+func (p *Parser) setValue(target reflect.Value, value string, field Field) error {
+	// Handle times with a layout tag, which overrides RFC 3339
+	if target.Type() == timeType && field.Layout != "" {
+		t, err := time.Parse(field.Layout, value)
+		if err != nil {
+			return err
+		}
+		target.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	// Handle types with a registered decoder first
+	if decode, ok := p.decoder(target.Type()); ok {
+		decoded, err := decode(value)
 		if err != nil {
 			return err
 		}
-		field.Value.Set(reflect.ValueOf(duration))
+		decodedVal := reflect.ValueOf(decoded)
+		if !decodedVal.IsValid() || !decodedVal.Type().AssignableTo(target.Type()) {
+			return fmt.Errorf("decoder for %s returned %T", target.Type(), decoded)
+		}
+		target.Set(decodedVal)
 		return nil
 	}
 
-	switch field.Type.Kind() {
+	// Handle types that parse themselves
+	switch v := target.Addr().Interface().(type) {
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText([]byte(value))
+	case flag.Value:
+		return v.Set(value)
+	}
+
+	switch target.Kind() {
 	case reflect.String:
-		field.Value.SetString(value)
+		target.SetString(value)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.Unit == unitBytes {
+			size, err := parseByteSize(value)
+			if err != nil {
+				return err
+			}
+			if size > math.MaxInt64 || target.OverflowInt(int64(size)) {
+				return fmt.Errorf("byte size %q out of range for %s", value, target.Type())
+			}
+			target.SetInt(int64(size))
+			return nil
+		}
 		intVal, err := strconv.ParseInt(value, 10, 64)
 		if err != nil {
 			return err
 		}
-		field.Value.SetInt(intVal)
+		target.SetInt(intVal)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if field.Unit == unitBytes {
+			size, err := parseByteSize(value)
+			if err != nil {
+				return err
+			}
+			if target.OverflowUint(uint64(size)) {
+				return fmt.Errorf("byte size %q out of range for %s", value, target.Type())
+			}
+			target.SetUint(uint64(size))
+			return nil
+		}
 		uintVal, err := strconv.ParseUint(value, 10, 64)
 		if err != nil {
 			return err
 		}
-		field.Value.SetUint(uintVal)
+		target.SetUint(uintVal)
 	case reflect.Float32, reflect.Float64:
 		floatVal, err := strconv.ParseFloat(value, 64)
 		if err != nil {
 			return err
 		}
-		field.Value.SetFloat(floatVal)
+		target.SetFloat(floatVal)
 	case reflect.Bool:
 		boolVal, err := strconv.ParseBool(value)
 		if err != nil {
 			return err
 		}
-		field.Value.SetBool(boolVal)
+		target.SetBool(boolVal)
 	case reflect.Slice:
 		// Handle slices (e.g., comma-separated values)
-		if field.Type.Elem().Kind() == reflect.String {
-			parts := strings.Split(value, ",")
-			slice := reflect.MakeSlice(field.Type, len(parts), len(parts))
-			for i, part := range parts {
-				slice.Index(i).SetString(strings.TrimSpace(part))
+		elems, err := splitList(value, field.Sep)
+		if err != nil {
+			return err
+		}
+		return p.setSlice(target, elems, field)
+	case reflect.Map:
+		// Handle maps (e.g., comma-separated key=value pairs)
+		entries, err := splitMapEntries(value, field.Sep)
+		if err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(target.Type(), len(entries))
+		for _, entry := range entries {
+			key := reflect.New(target.Type().Key()).Elem()
+			if err := p.setValue(key, entry[0], field); err != nil {
+				return fmt.Errorf("invalid key %q: %v", entry[0], err)
+			}
+			elem := reflect.New(target.Type().Elem()).Elem()
+			if err := p.setValue(elem, entry[1], field); err != nil {
+				return fmt.Errorf("invalid value for key %q: %v", entry[0], err)
 			}
-			field.Value.Set(slice)
+			m.SetMapIndex(key, elem)
 		}
+		target.Set(m)
 	}
 	return nil
 }
 
+// setSlice converts each element to the slice's element type and stores them
+func (p *Parser) setSlice(target reflect.Value, elems []string, field Field) error {
+	slice := reflect.MakeSlice(target.Type(), len(elems), len(elems))
+	for i, elem := range elems {
+		if err := p.setValue(slice.Index(i), elem, field); err != nil {
+			return fmt.Errorf("invalid element %d %q: %v", i, elem, err)
+		}
+	}
+	target.Set(slice)
+	return nil
+}
+
+// splitList splits value on sep, trimming whitespace around each element.
+// An element may be wrapped in double quotes (supporting \", \\, \n and \t
+// escapes) or single quotes (taken literally) to include sep, and outside of
+// quotes a backslash escapes sep or another backslash.
+func splitList(value, sep string) ([]string, error) {
+	var elems []string
+	var buf strings.Builder
+	keep := 0 // Length of buf that must not be trimmed (quoted or escaped text)
+
+	for i := 0; i <= len(value); {
+		// End of element
+		if i == len(value) || strings.HasPrefix(value[i:], sep) {
+			elem := buf.String()
+			elems = append(elems, elem[:keep]+strings.TrimRight(elem[keep:], " \t"))
+			buf.Reset()
+			keep = 0
+			i += len(sep)
+			continue
+		}
+
+		switch c := value[i]; {
+		case (c == ' ' || c == '\t') && buf.Len() == 0:
+			// Skip leading whitespace
+			i++
+		case (c == '"' || c == '\'') && buf.Len() == 0:
+			end := closingQuote(value[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in %q", value)
+			}
+			quoted := value[i+1 : i+1+end]
+			if c == '"' {
+				quoted = unescapeQuoted(quoted)
+			}
+			buf.WriteString(quoted)
+			keep = buf.Len()
+			i += end + 2
+		case c == '\\' && strings.HasPrefix(value[i+1:], sep):
+			buf.WriteString(sep)
+			keep = buf.Len()
+			i += 1 + len(sep)
+		case c == '\\' && strings.HasPrefix(value[i+1:], `\`):
+			buf.WriteByte('\\')
+			keep = buf.Len()
+			i += 2
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+
+	return elems, nil
+}
+
+// quoteElem quotes s if needed so that splitList yields it unchanged
+func quoteElem(s, sep string) string {
+	if !strings.Contains(s, sep) && !strings.Contains(s, `\`) &&
+		!strings.HasPrefix(s, `"`) && !strings.HasPrefix(s, "'") && strings.TrimSpace(s) == s {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// splitMapEntries splits "k1=v1,k2=v2" into key/value pairs, using sep
+// between entries and the quoting rules of splitList
+func splitMapEntries(value, sep string) ([][2]string, error) {
+	parts, err := splitList(value, sep)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries [][2]string
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid map entry %q: expected key=value", part)
+		}
+		entries = append(entries, [2]string{strings.TrimSpace(key), strings.TrimSpace(val)})
+	}
+	return entries, nil
+}
+
+// indirectType returns the type a pointer type points to, or t itself
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// defaultString renders the default value of field for help output, showing
+// byte sizes in their shortest form (e.g. 1048576 as 1MiB)
+func (p *Parser) defaultString(field Field) string {
+	if field.Unit == unitBytes || p.valueType(field.Type) == byteSizeType {
+		if size, err := parseByteSize(field.DefaultVal); err == nil {
+			return size.String()
+		}
+	}
+	return field.DefaultVal
+}
+
 // PrintHelp prints a formatted help message showing all configuration options
 func (p *Parser) PrintHelp() {
 	fmt.Println("Usage: " + os.Args[0] + " [options]")
@@ -453,7 +987,7 @@ func (p *Parser) PrintHelp() {
 				flagLen += 2 + len(name) // --xxx
 			}
 		}
-		if field.Type.Kind() != reflect.Bool {
+		if !p.isBoolFlag(field.Type) {
 			flagLen += 8 // " <value>"
 		}
 		if flagLen > maxWidth {
@@ -473,6 +1007,9 @@ func (p *Parser) PrintHelp() {
 
 	// Print help flag
 	fmt.Printf("  -h, --help%s Show this help message\n", strings.Repeat(" ", maxWidth-10))
+
+	// Print constraints between fields
+	p.printConstraints()
 }
 
 func (p *Parser) printFieldHelp(field fieldInfo, width int) {
@@ -487,7 +1024,7 @@ func (p *Parser) printFieldHelp(field fieldInfo, width int) {
 	}
 	flag := strings.Join(flagParts, ", ")
 
-	if field.Type.Kind() != reflect.Bool {
+	if !p.isBoolFlag(field.Type) {
 		flag += " <value>"
 	}
 
@@ -498,14 +1035,31 @@ func (p *Parser) printFieldHelp(field fieldInfo, width int) {
 	}
 
 	// Add default value info
-	if field.DefaultVal != "" && field.Type.Kind() != reflect.Bool {
-		desc += fmt.Sprintf(" (default: %s)", field.DefaultVal)
+	if field.DefaultVal != "" && !p.isBoolFlag(field.Type) {
+		desc += fmt.Sprintf(" (default: %s)", p.defaultString(field.Field))
+	}
+
+	// Add allowed values
+	if len(field.OneOf) > 0 {
+		desc += fmt.Sprintf(" (one of: %s)", strings.Join(field.OneOf, ", "))
+	}
+	if field.Pattern != "" {
+		desc += fmt.Sprintf(" (pattern: %s)", field.Pattern)
 	}
 
 	// Add required marker
 	if field.Required {
 		desc += " [required]"
 	}
+	if field.RequiredIf != "" {
+		desc += fmt.Sprintf(" [required if %s]", field.RequiredIf)
+	}
+	if field.RequiredUnless != "" {
+		desc += fmt.Sprintf(" [required unless %s]", field.RequiredUnless)
+	}
+	if field.RequiredWith != "" {
+		desc += fmt.Sprintf(" [required with %s]", field.RequiredWith)
+	}
 
 	// Print formatted line
 	fmt.Printf("  %-*s %s\n", width, flag, desc)
@@ -0,0 +1,124 @@
+package configlib
+
+import (
+	"encoding"
+	"flag"
+	"net"
+	"net/url"
+	"reflect"
+	"time"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	flagValueType       = reflect.TypeOf((*flag.Value)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// timeLayouts maps names accepted by the layout tag to time package layouts
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+// builtinDecoders holds decoders for standard library types that do not
+// implement encoding.TextUnmarshaler. Types such as net.IP, netip.Addr and
+// netip.Prefix are handled through their UnmarshalText methods.
+var builtinDecoders = map[reflect.Type]func(string) (any, error){
+	reflect.TypeOf(time.Duration(0)):   decodeDuration,
+	reflect.TypeOf(&time.Location{}):   decodeLocation,
+	reflect.TypeOf(&url.URL{}):         decodeURLPtr,
+	reflect.TypeOf(url.URL{}):          decodeURL,
+	reflect.TypeOf(net.HardwareAddr{}): decodeHardwareAddr,
+}
+
+// WithDecoder registers decode for fields of type typ, taking precedence over
+// built-in conversions. decode must return a value assignable to typ. This
+// allows third-party types that cannot implement encoding.TextUnmarshaler.
+func WithDecoder(typ reflect.Type, decode func(string) (any, error)) Option {
+	return func(p *Parser) {
+		p.decoders[typ] = decode
+	}
+}
+
+// decoder returns the decoder for t, preferring ones registered with WithDecoder
+func (p *Parser) decoder(t reflect.Type) (func(string) (any, error), bool) {
+	if decode, ok := p.decoders[t]; ok {
+		return decode, true
+	}
+	decode, ok := builtinDecoders[t]
+	return decode, ok
+}
+
+// isLeafType reports whether t is set from a single value by a decoder,
+// encoding.TextUnmarshaler or flag.Value rather than by its kind
+func (p *Parser) isLeafType(t reflect.Type) bool {
+	if _, ok := p.decoder(t); ok {
+		return true
+	}
+	ptr := reflect.PointerTo(t)
+	return ptr.Implements(textUnmarshalerType) || ptr.Implements(flagValueType)
+}
+
+// valueType returns the type that values for a field of type t are parsed
+// into: the type a pointer points to, unless a decoder handles the pointer type
+func (p *Parser) valueType(t reflect.Type) reflect.Type {
+	if _, ok := p.decoder(t); ok {
+		return t
+	}
+	return indirectType(t)
+}
+
+// isBoolFlag reports whether fields of type t are registered as boolean flags
+// that take no value
+func (p *Parser) isBoolFlag(t reflect.Type) bool {
+	t = p.valueType(t)
+	return t.Kind() == reflect.Bool && !p.isLeafType(t)
+}
+
+func decodeDuration(s string) (any, error) {
+	return time.ParseDuration(s)
+}
+
+// decodeLocation resolves IANA time zone names such as "Europe/Berlin", as
+// well as "UTC" and "Local"
+func decodeLocation(s string) (any, error) {
+	return time.LoadLocation(s)
+}
+
+// timeLayout resolves a layout tag, which may name a time package layout
+// (e.g. "DateOnly") or be a layout itself (e.g. "2006-01-02 15:04")
+func timeLayout(tag string) string {
+	if layout, ok := timeLayouts[tag]; ok {
+		return layout
+	}
+	return tag
+}
+
+func decodeURLPtr(s string) (any, error) {
+	return url.Parse(s)
+}
+
+func decodeURL(s string) (any, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return *u, nil
+}
+
+func decodeHardwareAddr(s string) (any, error) {
+	return net.ParseMAC(s)
+}
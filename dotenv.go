@@ -0,0 +1,147 @@
+package configlib
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// WithDotEnv loads variables from the given dotenv files. They are used for
+// fields whose environment variable is not set, without modifying the process
+// environment. Files that do not exist are skipped, and later files take
+// precedence over earlier ones.
+func WithDotEnv(paths ...string) Option {
+	return func(p *Parser) {
+		p.dotEnvPaths = append(p.dotEnvPaths, paths...)
+	}
+}
+
+func (p *Parser) loadDotEnv() error {
+	for _, path := range p.dotEnvPaths {
+		content, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading dotenv file: %v", err)
+		}
+
+		vars, err := parseDotEnv(string(content))
+		if err != nil {
+			return fmt.Errorf("error parsing dotenv file %s: %v", path, err)
+		}
+
+		for k, v := range vars {
+			p.dotEnv[k] = v
+		}
+	}
+	return nil
+}
+
+// parseDotEnv parses dotenv syntax:
+//
+//	# comment
+//	export KEY=value # trailing comment
+//	SINGLE='literal $value, may span lines' # trailing comment
+//	DOUBLE="supports \n, \t, \" and \\ escapes
+//	and may span lines"
+func parseDotEnv(content string) (map[string]string, error) {
+	vars := make(map[string]string)
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNum := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimLeft(rest, " \t")
+		}
+
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=value", lineNum)
+		}
+		key = strings.TrimSpace(key)
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: invalid key %q", lineNum, key)
+		}
+		val = strings.TrimLeft(val, " \t")
+
+		if val == "" || (val[0] != '"' && val[0] != '\'') {
+			// Unquoted values end at a comment preceded by whitespace
+			for i := 1; i < len(val); i++ {
+				if val[i] == '#' && (val[i-1] == ' ' || val[i-1] == '\t') {
+					val = val[:i]
+					break
+				}
+			}
+			vars[key] = strings.TrimSpace(val)
+			continue
+		}
+
+		// Quoted values may continue onto following lines
+		quote := val[0]
+		val = val[1:]
+		for {
+			if end := closingQuote(val, quote); end >= 0 {
+				// Only a comment may follow the closing quote
+				if rest := strings.TrimSpace(val[end+1:]); rest != "" && rest[0] != '#' {
+					return nil, fmt.Errorf("line %d: unexpected %q after quoted value", i+1, rest)
+				}
+				val = val[:end]
+				break
+			}
+			i++
+			if i == len(lines) {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", lineNum)
+			}
+			val += "\n" + lines[i]
+		}
+
+		if quote == '"' {
+			val = unescapeQuoted(val)
+		}
+		vars[key] = val
+	}
+
+	return vars, nil
+}
+
+// closingQuote returns the index of the unescaped closing quote in s, or -1
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeQuoted(s string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			buf.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			buf.WriteByte('\n')
+		case 't':
+			buf.WriteByte('\t')
+		case 'r':
+			buf.WriteByte('\r')
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,151 @@
+package configlib_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bherbruck/configlib"
+)
+
+func TestDotEnv(t *testing.T) {
+	path := writeConfigFile(t, ".env", `# Local development settings
+export HOST=dotenv-host
+export	PORT=9000 # trailing comment
+SINGLE='literal \n value' # trailing comment
+DOUBLE="line one\nline \"two\""
+MULTILINE="first
+second"
+TABBED=value	# comment after a tab
+EMPTY=
+`)
+
+	type Config struct {
+		Host      string `env:"HOST" default:"localhost"`
+		Port      int    `env:"PORT"`
+		Single    string `env:"SINGLE"`
+		Double    string `env:"DOUBLE"`
+		Multiline string `env:"MULTILINE"`
+		Tabbed    string `env:"TABBED"`
+		Empty     string `env:"EMPTY" default:"fallback"`
+	}
+
+	var cfg Config
+	parser := configlib.NewParser(
+		configlib.WithDotEnv(path),
+		configlib.WithEnvLookup(envLookup(map[string]string{"HOST": "env-host"})),
+	)
+	err := parser.ParseArgs(&cfg, nil)
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	expected := Config{
+		Host:      "env-host",
+		Port:      9000,
+		Single:    `literal \n value`,
+		Double:    "line one\nline \"two\"",
+		Multiline: "first\nsecond",
+		Tabbed:    "value",
+		Empty:     "fallback",
+	}
+	if cfg != expected {
+		t.Errorf("ParseArgs() got = %+v, want %+v", cfg, expected)
+	}
+}
+
+func TestDotEnvDoesNotModifyEnvironment(t *testing.T) {
+	path := writeConfigFile(t, ".env", "CONFIGLIB_DOTENV_TEST=value\n")
+
+	type Config struct {
+		Value string `env:"CONFIGLIB_DOTENV_TEST"`
+	}
+
+	var cfg Config
+	parser := configlib.NewParser(configlib.WithDotEnv(path))
+	err := parser.ParseArgs(&cfg, nil)
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if cfg.Value != "value" {
+		t.Errorf("Value = %s, want value", cfg.Value)
+	}
+	if _, ok := os.LookupEnv("CONFIGLIB_DOTENV_TEST"); ok {
+		t.Error("WithDotEnv should not set process environment variables")
+	}
+}
+
+func TestDotEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, ".env")
+	local := filepath.Join(dir, ".env.local")
+	os.WriteFile(base, []byte("HOST=base\nPORT=9000\n"), 0o600)
+	os.WriteFile(local, []byte("HOST=local\n"), 0o600)
+
+	var cfg SimpleConfig
+	parser := configlib.NewParser(
+		configlib.WithDotEnv(base, local, filepath.Join(dir, "missing.env")),
+		configlib.WithEnvLookup(envLookup(map[string]string{"REQUIRED": "x"})),
+	)
+	err := parser.ParseArgs(&cfg, nil)
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	// Later files take precedence and missing files are skipped
+	if cfg.Host != "local" {
+		t.Errorf("Host = %s, want local", cfg.Host)
+	}
+	if cfg.Port != 9000 {
+		t.Errorf("Port = %d, want 9000", cfg.Port)
+	}
+}
+
+func TestDotEnvErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		errMsg string
+	}{
+		{
+			name:   "missing equals",
+			body:   "HOST\n",
+			errMsg: "line 1: expected KEY=value",
+		},
+		{
+			name:   "invalid key",
+			body:   "# comment\nMY HOST=x\n",
+			errMsg: `line 2: invalid key "MY HOST"`,
+		},
+		{
+			name:   "unterminated quote",
+			body:   "HOST=\"abc\nPORT=1\n",
+			errMsg: "line 1: unterminated quoted value",
+		},
+		{
+			name:   "text after quoted value",
+			body:   "PORT=1\nHOST=\"abc\" def\n",
+			errMsg: `line 2: unexpected "def" after quoted value`,
+		},
+		{
+			name:   "text after multiline quoted value",
+			body:   "HOST='abc\ndef'ghi\n",
+			errMsg: `line 2: unexpected "ghi" after quoted value`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, ".env", tt.body)
+
+			var cfg SimpleConfig
+			parser := configlib.NewParser(configlib.WithDotEnv(path))
+			err := parser.ParseArgs(&cfg, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
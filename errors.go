@@ -0,0 +1,196 @@
+package configlib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MultiError holds every problem found once values are applied: missing
+// required fields, invalid values, broken field groups and errors returned
+// by Validate. It works with errors.As and errors.Is through Unwrap.
+type MultiError struct {
+	Errors []error
+}
+
+// Error lists the problems under a heading for each kind
+func (e *MultiError) Error() string {
+	var lines []string
+	heading := ""
+	for _, err := range e.Errors {
+		h := errorHeading(err)
+		if h == "" {
+			lines = append(lines, err.Error())
+		} else {
+			if h != heading {
+				lines = append(lines, h+":")
+			}
+			lines = append(lines, "  - "+err.Error())
+		}
+		heading = h
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the individual problems
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+func errorHeading(err error) string {
+	switch err.(type) {
+	case *ValidationError:
+		return "invalid fields"
+	case *GroupError:
+		return "constraint violations"
+	case *StructError:
+		return "validation errors"
+	default:
+		return ""
+	}
+}
+
+// MissingField describes a required field that has no value
+type MissingField struct {
+	FieldPath string
+	EnvName   string
+	CliName   string
+	Condition string // Why the field is required, e.g. "required if Mode=tls", or empty for the required tag
+}
+
+func (f MissingField) String() string {
+	var sources []string
+	if f.EnvName != "" {
+		sources = append(sources, fmt.Sprintf("env: %s", f.EnvName))
+	}
+	if f.CliName != "" {
+		sources = append(sources, fmt.Sprintf("flag: --%s", f.CliName))
+	}
+	s := fmt.Sprintf("%s (%s)", f.FieldPath, strings.Join(sources, ", "))
+	if f.Condition != "" {
+		s += " " + f.Condition
+	}
+	return s
+}
+
+// MissingFieldsError lists the required fields that have no value
+type MissingFieldsError struct {
+	Fields []MissingField
+}
+
+func (e *MissingFieldsError) Error() string {
+	lines := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		lines = append(lines, f.String())
+	}
+	return "missing required fields:\n  - " + strings.Join(lines, "\n  - ")
+}
+
+// FieldError reports a value that could not be converted to its field's type
+type FieldError struct {
+	FieldPath string
+	Source    string // Name of the source that supplied the value, e.g. "env"
+	Value     string
+	Err       error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("error setting field %s: %v", e.FieldPath, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// redactedError hides the value of a secret field from the message of a
+// conversion error, while errors.Is and errors.As still reach the error
+type redactedError struct {
+	err   error
+	value string
+}
+
+func (e *redactedError) Error() string {
+	return e.redact(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redact replaces the secret value in msg
+func (e *redactedError) redact(msg string) string {
+	return strings.ReplaceAll(msg, e.value, redacted)
+}
+
+// newFieldError returns a FieldError for value, redacted if field is secret
+func newFieldError(field Field, source, value string, err error) *FieldError {
+	if field.Secret {
+		err = &redactedError{err: err, value: value}
+		value = redacted
+	}
+	return &FieldError{FieldPath: field.FieldPath, Source: source, Value: value, Err: err}
+}
+
+// flagError keeps the flag package's message for an invalid flag value while
+// exposing the FieldError behind it to errors.As
+type flagError struct {
+	msg string
+	err *FieldError
+}
+
+func (e *flagError) Error() string {
+	return e.msg
+}
+
+func (e *flagError) Unwrap() error {
+	return e.err
+}
+
+// ValidationError reports a value that breaks one of its field's validation
+// tags, such as oneof, min or pattern
+type ValidationError struct {
+	FieldPath string
+	Value     string // The offending value, or slice element or map value
+	Err       error
+}
+
+func (e *ValidationError) Error() string {
+	return e.FieldPath + ": " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// GroupError reports a broken exclusive or atleastone field group
+type GroupError struct {
+	Group     string
+	Exclusive bool     // Whether at most one field may be set, rather than at least one
+	Fields    []string // FieldPath of each field in the group
+	Set       []string // FieldPath of each field in the group that has a value
+}
+
+func (e *GroupError) Error() string {
+	if e.Exclusive {
+		return fmt.Sprintf("group %s: at most one of %s may be set, got %s",
+			e.Group, strings.Join(e.Fields, ", "), strings.Join(e.Set, ", "))
+	}
+	return fmt.Sprintf("group %s: at least one of %s is required", e.Group, strings.Join(e.Fields, ", "))
+}
+
+// StructError wraps an error returned by the Validate method of the config
+// struct, or of the nested struct at StructPath
+type StructError struct {
+	StructPath string // Empty for the root struct
+	Err        error
+}
+
+func (e *StructError) Error() string {
+	if e.StructPath == "" {
+		return e.Err.Error()
+	}
+	return e.StructPath + ": " + e.Err.Error()
+}
+
+func (e *StructError) Unwrap() error {
+	return e.Err
+}
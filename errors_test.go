@@ -1,10 +1,15 @@
 package configlib_test
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bherbruck/configlib"
 )
@@ -122,3 +127,234 @@ func TestInvalidValues(t *testing.T) {
 		})
 	}
 }
+
+func TestMissingFieldsErrorAs(t *testing.T) {
+	var cfg NestedConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{"DB_HOST=db"}))
+	err := parser.ParseArgs(&cfg, nil)
+
+	var missing *configlib.MissingFieldsError
+	if !errors.As(err, &missing) {
+		t.Fatalf("errors.As(%v, *MissingFieldsError) = false", err)
+	}
+
+	expected := []configlib.MissingField{
+		{FieldPath: "Server.Host", EnvName: "SERVER_HOST", CliName: "server-host"},
+		{FieldPath: "Database.Password", EnvName: "DB_PASSWORD", CliName: "db-password"},
+	}
+	if !reflect.DeepEqual(missing.Fields, expected) {
+		t.Errorf("MissingFieldsError.Fields = %+v, want %+v", missing.Fields, expected)
+	}
+
+	var multi *configlib.MultiError
+	if !errors.As(err, &multi) || len(multi.Errors) != 1 {
+		t.Errorf("errors.As(%v, *MultiError) should hold only the missing fields", err)
+	}
+}
+
+func TestFieldErrorAs(t *testing.T) {
+	tests := []struct {
+		name     string
+		environ  []string
+		cliArgs  []string
+		expected configlib.FieldError
+		errMsg   string
+	}{
+		{
+			name:     "from env",
+			environ:  []string{"PORT=eighty", "REQUIRED=x"},
+			expected: configlib.FieldError{FieldPath: "Port", Source: "env", Value: "eighty"},
+			errMsg:   "error setting field Port",
+		},
+		{
+			name:     "from flag",
+			environ:  []string{"REQUIRED=x"},
+			cliArgs:  []string{"--port", "eighty"},
+			expected: configlib.FieldError{FieldPath: "Port", Source: "flag", Value: "eighty"},
+			errMsg:   `invalid value "eighty" for flag -port: invalid integer value: eighty`,
+		},
+		{
+			name:     "from flag with shorthand",
+			environ:  []string{"REQUIRED=x"},
+			cliArgs:  []string{"-t", "soon"},
+			expected: configlib.FieldError{FieldPath: "Timeout", Source: "flag", Value: "soon"},
+			errMsg:   `invalid value "soon" for flag -t`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg struct {
+				Port     int           `env:"PORT" flag:"port"`
+				Timeout  time.Duration `env:"TIMEOUT" flag:"timeout,t"`
+				Required string        `env:"REQUIRED" required:"true"`
+			}
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+
+			var fieldErr *configlib.FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("errors.As(%v, *FieldError) = false", err)
+			}
+			if fieldErr.FieldPath != tt.expected.FieldPath || fieldErr.Source != tt.expected.Source ||
+				fieldErr.Value != tt.expected.Value || fieldErr.Err == nil {
+				t.Errorf("FieldError = %+v, want %+v with the conversion error", fieldErr, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFieldErrorWrapsConversionError(t *testing.T) {
+	var cfg SimpleConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{"PORT=eighty", "REQUIRED=x"}))
+	err := parser.ParseArgs(&cfg, nil)
+
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Errorf("errors.As(%v, *strconv.NumError) = false, want the conversion error to be wrapped", err)
+	}
+}
+
+func TestSecretValuesRedactedFromErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+		errMsg  string
+	}{
+		{
+			name:    "pattern violation",
+			environ: []string{"PASSWORD=hunter2"},
+			errMsg:  "Password: *** does not match pattern",
+		},
+		{
+			name:    "slice element not allowed",
+			environ: []string{"PASSWORD=Hunter22", "KEYS=alpha,hunter2"},
+			errMsg:  "Keys: element 1 *** is not one of",
+		},
+		{
+			name:    "conversion error from env",
+			environ: []string{"PASSWORD=Hunter22", "PIN=hunter2"},
+			errMsg:  "error setting field PIN",
+		},
+		{
+			name:    "conversion error from flag",
+			environ: []string{"PASSWORD=Hunter22"},
+			cliArgs: []string{"--pin", "hunter2"},
+			errMsg:  `invalid value "***" for flag -pin`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg struct {
+				Password string   `env:"PASSWORD" pattern:"[A-Z].*[0-9]" secret:"true"`
+				Keys     []string `env:"KEYS" oneof:"alpha,beta" secret:"true"`
+				PIN      int      `env:"PIN" flag:"pin" secret:"true"`
+			}
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+			if err != nil && strings.Contains(err.Error(), "hunter2") {
+				t.Errorf("ParseArgs() error = %v, want secret value redacted", err)
+			}
+
+			var fieldErr *configlib.FieldError
+			if errors.As(err, &fieldErr) && fieldErr.Value != "***" {
+				t.Errorf("FieldError.Value = %q, want ***", fieldErr.Value)
+			}
+			var validationErr *configlib.ValidationError
+			if errors.As(err, &validationErr) && validationErr.Value != "***" {
+				t.Errorf("ValidationError.Value = %q, want ***", validationErr.Value)
+			}
+		})
+	}
+}
+
+var errNoWorkers = errors.New("no workers")
+
+type structuredConfig struct {
+	Token     string `env:"TOKEN" exclusive:"auth"`
+	TokenFile string `env:"TOKEN_FILE" exclusive:"auth"`
+	Level     string `env:"LEVEL" oneof:"debug,info"`
+	Host      string `env:"HOST" required:"true"`
+	Mode      string `env:"MODE"`
+	Cert      string `env:"CERT" required_if:"Mode=tls"`
+	Workers   struct {
+		Count int `env:"WORKERS" default:"0"`
+	}
+}
+
+func (c *structuredConfig) Validate() error {
+	if c.Workers.Count == 0 {
+		return fmt.Errorf("workers: %w", errNoWorkers)
+	}
+	return nil
+}
+
+func TestMultiErrorAs(t *testing.T) {
+	var cfg structuredConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{
+		"TOKEN=a",
+		"TOKEN_FILE=b",
+		"LEVEL=trace",
+		"MODE=tls",
+	}))
+	err := parser.ParseArgs(&cfg, nil)
+
+	var multi *configlib.MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("errors.As(%v, *MultiError) = false", err)
+	}
+	if len(multi.Errors) != 4 {
+		t.Errorf("MultiError.Errors has %d errors, want 4: %v", len(multi.Errors), multi.Errors)
+	}
+
+	var missing *configlib.MissingFieldsError
+	if !errors.As(err, &missing) || len(missing.Fields) != 2 || missing.Fields[1].Condition != "required if Mode=tls" {
+		t.Errorf("MissingFieldsError = %+v, want Host and Cert required if Mode=tls", missing)
+	}
+
+	var validation *configlib.ValidationError
+	if !errors.As(err, &validation) || validation.FieldPath != "Level" || validation.Value != "trace" {
+		t.Errorf("ValidationError = %+v, want Level with value trace", validation)
+	}
+
+	var group *configlib.GroupError
+	if !errors.As(err, &group) || group.Group != "auth" || !group.Exclusive ||
+		!reflect.DeepEqual(group.Set, []string{"Token", "TokenFile"}) {
+		t.Errorf("GroupError = %+v, want exclusive group auth with Token and TokenFile set", group)
+	}
+
+	var structErr *configlib.StructError
+	if !errors.As(err, &structErr) || structErr.StructPath != "" {
+		t.Errorf("StructError = %+v, want root struct error", structErr)
+	}
+	if !errors.Is(err, errNoWorkers) {
+		t.Errorf("errors.Is(%v, errNoWorkers) = false, want Validate errors to be wrapped", err)
+	}
+
+	// The container can be combined with other errors
+	joined := errors.Join(errors.New("startup failed"), err)
+	if !errors.As(joined, &group) || !errors.Is(joined, errNoWorkers) {
+		t.Errorf("errors.Join should preserve the structured errors, got %v", joined)
+	}
+
+	expected := "missing required fields:\n" +
+		"  - Host (env: HOST, flag: --host)\n" +
+		"  - Cert (env: CERT, flag: --cert) required if Mode=tls\n" +
+		"invalid fields:\n" +
+		"  - Level: \"trace\" is not one of debug, info\n" +
+		"constraint violations:\n" +
+		"  - group auth: at most one of Token, TokenFile may be set, got Token, TokenFile\n" +
+		"validation errors:\n" +
+		"  - workers: no workers"
+	if err.Error() != expected {
+		t.Errorf("Error() = %q, want %q", err.Error(), expected)
+	}
+}
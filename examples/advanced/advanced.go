@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 
@@ -50,6 +51,9 @@ func main() {
 	var cfg1 ConfigNoAutoEnv
 	parser1 := configlib.NewParser(configlib.WithDisableAutoEnv())
 	err := parser1.Parse(&cfg1)
+	if errors.Is(err, configlib.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Printf("Error parsing config: %v", err)
 	}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 
 	"github.com/bherbruck/configlib"
@@ -24,6 +25,9 @@ func main() {
 	// Parse config from environment variables, command line flags, and defaults
 	var cfg Config
 	err := configlib.Parse(&cfg)
+	if errors.Is(err, configlib.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Printf("Error parsing config: %v", err)
 		return
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -31,6 +32,9 @@ func main() {
 	var cfg Config
 
 	err := configlib.Parse(&cfg)
+	if errors.Is(err, configlib.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
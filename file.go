@@ -0,0 +1,440 @@
+package configlib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile holds the decoded contents of a configuration file
+type configFile struct {
+	path   string
+	format string // Format name, also used as the struct tag for key overrides
+	data   map[string]any
+}
+
+// FileDecoder decodes the contents of a configuration file into nested maps.
+// Objects and sections become map[string]any values; leaves may be strings,
+// bools, numbers, time.Time values or []any lists of those.
+type FileDecoder func([]byte) (map[string]any, error)
+
+// fileFormat describes how to decode a configuration file format
+type fileFormat struct {
+	name   string
+	decode FileDecoder
+}
+
+// fileFormats maps file extensions to their built-in formats
+var fileFormats = map[string]fileFormat{
+	".json": {name: "json", decode: decodeJSON},
+	".yaml": {name: "yaml", decode: decodeYAML},
+	".yml":  {name: "yaml", decode: decodeYAML},
+	".toml": {name: "toml", decode: decodeTOML},
+	".ini":  {name: "ini", decode: decodeINI},
+}
+
+// WithConfigFile adds a configuration file whose values sit between environment
+// variables and defaults. The format is chosen by the file extension. When
+// given more than once, later files take precedence over earlier ones.
+func WithConfigFile(path string) Option {
+	return func(p *Parser) {
+		p.configPaths = append(p.configPaths, path)
+	}
+}
+
+// WithFileDecoder registers decode for config files with the given extension
+// (e.g. ".conf"), replacing any built-in decoder for it. Keys can be overridden
+// with the struct tag named format.
+func WithFileDecoder(ext, format string, decode FileDecoder) Option {
+	return func(p *Parser) {
+		p.fileFormats[strings.ToLower(ext)] = fileFormat{name: format, decode: decode}
+	}
+}
+
+func (p *Parser) loadConfigFiles() error {
+	for _, path := range p.configPaths {
+		ext := strings.ToLower(filepath.Ext(path))
+		format, ok := p.fileFormats[ext]
+		if !ok {
+			format, ok = fileFormats[ext]
+		}
+		if !ok {
+			return fmt.Errorf("unsupported config file format: %s", path)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading config file: %v", err)
+		}
+
+		data, err := format.decode(content)
+		if err != nil {
+			return fmt.Errorf("error parsing config file %s: %v", path, err)
+		}
+
+		p.configFiles = append(p.configFiles, configFile{
+			path:   path,
+			format: format.name,
+			data:   data,
+		})
+	}
+	return nil
+}
+
+// Name implements Source
+func (f configFile) Name() string {
+	return "file " + f.path
+}
+
+// Lookup implements Source
+func (f configFile) Lookup(field Field) (string, bool) {
+	node, _, ok := f.find(field)
+	if !ok {
+		return "", false
+	}
+	return fileValueString(node, field.Sep, field.Layout)
+}
+
+// find returns the value for field and the path of keys as spelled in the file
+func (f configFile) find(field Field) (any, []string, bool) {
+	node, path, ok := f.findKey(fileKey(field, f.format))
+	if !ok {
+		return nil, nil, false
+	}
+
+	// Only map fields take their value from an object
+	if _, ok := node.(map[string]any); ok && indirectType(field.Type).Kind() != reflect.Map {
+		return nil, nil, false
+	}
+
+	return node, path, true
+}
+
+// findKey returns the value at key and the path of keys as spelled in the file
+func (f configFile) findKey(key []string) (any, []string, bool) {
+	if key == nil {
+		return nil, nil, false
+	}
+
+	var node any = f.data
+	path := make([]string, 0, len(key))
+	for _, name := range key {
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return nil, nil, false
+		}
+		name, node, ok = lookupKey(obj, name)
+		if !ok {
+			return nil, nil, false
+		}
+		path = append(path, name)
+	}
+	return node, path, true
+}
+
+// checkUnknownKeys reports keys in the loaded config files that do not map to
+// any field
+func (p *Parser) checkUnknownKeys() error {
+	for _, f := range p.configFiles {
+		known := make(map[string]bool)
+		for _, field := range p.fields {
+			if _, path, ok := f.find(field.Field); ok {
+				known[strings.Join(path, ".")] = true
+			}
+		}
+		// Sections for nested structs may be empty, e.g. with all of their
+		// keys commented out
+		for _, s := range p.structs {
+			if s.path == "" {
+				continue
+			}
+			node, path, ok := f.findKey(fileKey(Field{FieldPath: s.path, Tags: s.tags}, f.format))
+			if obj, isObj := node.(map[string]any); ok && (node == nil || (isObj && len(obj) == 0)) {
+				known[strings.Join(path, ".")] = true
+			}
+		}
+
+		var unknown []string
+		collectUnknownKeys(f.data, "", known, &unknown)
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return fmt.Errorf("unknown keys in config file %s: %s", f.path, strings.Join(unknown, ", "))
+		}
+	}
+	return nil
+}
+
+func collectUnknownKeys(obj map[string]any, prefix string, known map[string]bool, unknown *[]string) {
+	for k, val := range obj {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if known[path] {
+			continue
+		}
+		if nested, ok := val.(map[string]any); ok && len(nested) > 0 {
+			collectUnknownKeys(nested, path, known, unknown)
+			continue
+		}
+		*unknown = append(*unknown, path)
+	}
+}
+
+// fileKey returns the key path of field within a file of the given format.
+// Each segment comes from the format's struct tag (e.g. `json:"port"`), the
+// `file` tag, or the Go field name, in that order. A nil key means the field
+// is excluded from files with a "-" tag.
+func fileKey(field Field, format string) []string {
+	key := strings.Split(field.FieldPath, ".")
+	for i, tag := range field.Tags {
+		for _, tagKey := range []string{format, "file"} {
+			name, _, _ := strings.Cut(tag.Get(tagKey), ",")
+			if name == "-" {
+				return nil
+			}
+			if name != "" {
+				key[i] = name
+				break
+			}
+		}
+	}
+	return key
+}
+
+// lookupKey finds name in obj, falling back to a case-insensitive match, and
+// returns the key as spelled in obj
+func lookupKey(obj map[string]any, name string) (string, any, bool) {
+	if val, ok := obj[name]; ok {
+		return name, val, true
+	}
+	for k, val := range obj {
+		if strings.EqualFold(k, name) {
+			return k, val, true
+		}
+	}
+	return "", nil, false
+}
+
+// fileValueString converts a decoded file value to the string form accepted
+// by setFieldValue. Lists and objects are joined with sep, quoting elements
+// that contain it, and times are formatted with layout (RFC 3339 if empty).
+func fileValueString(val any, sep, layout string) (string, bool) {
+	switch v := val.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case time.Time:
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		return v.Format(layout), true
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, elem := range v {
+			part, ok := fileValueString(elem, sep, layout)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, quoteElem(part, sep))
+		}
+		return strings.Join(parts, sep), true
+	case map[string]any:
+		// Objects at a field's key populate map fields
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(v))
+		for _, k := range keys {
+			part, ok := fileValueString(v[k], sep, layout)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, quoteElem(k+"="+part, sep))
+		}
+		return strings.Join(parts, sep), true
+	default:
+		return "", false
+	}
+}
+
+func decodeJSON(content []byte) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var data map[string]any
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	// Only whitespace may follow the object
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after top-level value at offset %d", decoder.InputOffset())
+	}
+	return data, nil
+}
+
+func decodeYAML(content []byte) (map[string]any, error) {
+	var data map[string]any
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = make(map[string]any)
+	}
+	for k, v := range data {
+		val, err := stringKeys(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		data[k] = val
+	}
+	return data, nil
+}
+
+// stringKeys converts the map[any]any values yaml.v3 decodes for mappings
+// with non-string keys, such as integers or booleans, to map[string]any
+func stringKeys(val any) (any, error) {
+	switch v := val.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, elem := range v {
+			key, ok := fileValueString(k, "", "")
+			if !ok {
+				return nil, fmt.Errorf("unsupported key %v", k)
+			}
+			elem, err := stringKeys(elem)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			m[key] = elem
+		}
+		return m, nil
+	case map[string]any:
+		for k, elem := range v {
+			elem, err := stringKeys(elem)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			v[k] = elem
+		}
+		return v, nil
+	case []any:
+		for i, elem := range v {
+			elem, err := stringKeys(elem)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", i, err)
+			}
+			v[i] = elem
+		}
+		return v, nil
+	default:
+		return val, nil
+	}
+}
+
+func decodeTOML(content []byte) (map[string]any, error) {
+	var data map[string]any
+	if err := toml.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// decodeINI decodes INI files. Sections map to nested objects, with dots in
+// section names (e.g. [server.tls]) nesting further. Keys before the first
+// section are top-level. Lines starting with ; or # are comments, as is
+// anything after whitespace and ; or # in a value. Values may be wrapped in
+// single or double quotes.
+func decodeINI(content []byte) (map[string]any, error) {
+	data := make(map[string]any)
+	section := data
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("line %d: unterminated section header", lineNum)
+			}
+			section = data
+			for _, name := range strings.Split(line[1:len(line)-1], ".") {
+				name = strings.TrimSpace(name)
+				if name == "" {
+					return nil, fmt.Errorf("line %d: empty section name", lineNum)
+				}
+				nested, ok := section[name].(map[string]any)
+				if !ok {
+					nested = make(map[string]any)
+					section[name] = nested
+				}
+				section = nested
+			}
+			continue
+		}
+
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNum)
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty key", lineNum)
+		}
+		section[key] = iniValue(strings.TrimSpace(val))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// iniValue unquotes an INI value and strips a trailing ; or # comment, which
+// must be preceded by whitespace in unquoted values
+func iniValue(val string) string {
+	if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') {
+		if end := strings.IndexByte(val[1:], val[0]); end >= 0 {
+			rest := strings.TrimSpace(val[end+2:])
+			if rest == "" || rest[0] == ';' || rest[0] == '#' {
+				return val[1 : end+1]
+			}
+		}
+	}
+	for i := 1; i < len(val); i++ {
+		if (val[i] == ';' || val[i] == '#') && (val[i-1] == ' ' || val[i-1] == '\t') {
+			return strings.TrimSpace(val[:i])
+		}
+	}
+	return val
+}
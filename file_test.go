@@ -0,0 +1,555 @@
+package configlib_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bherbruck/configlib"
+)
+
+type FileConfig struct {
+	Host    string        `env:"HOST" flag:"host" default:"localhost"`
+	Port    int           `env:"PORT" flag:"port" default:"8080"`
+	Debug   bool          `env:"DEBUG" flag:"debug"`
+	Timeout time.Duration `env:"TIMEOUT" flag:"timeout" default:"5s"`
+	Tags    []string      `env:"TAGS" flag:"tags"`
+	Server  struct {
+		TLS struct {
+			Port int    `env:"TLS_PORT" flag:"tls-port" default:"443"`
+			Cert string `json:"certificate" yaml:"certificate" toml:"certificate" ini:"certificate" env:"TLS_CERT" flag:"tls-cert"`
+		} `json:"tls" yaml:"tls" toml:"tls" ini:"tls"`
+	}
+}
+
+// writeConfigFile writes content to a file named name in a temporary directory
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	return path
+}
+
+func TestJSONConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{
+		"host": "file-host",
+		"port": 9000,
+		"debug": true,
+		"timeout": "1m",
+		"tags": ["a", "b"],
+		"server": {
+			"tls": {
+				"port": 8443,
+				"certificate": "/etc/cert.pem"
+			}
+		}
+	}`)
+
+	var cfg FileConfig
+	parser := configlib.NewParser(
+		configlib.WithConfigFile(path),
+		configlib.WithEnvLookup(envLookup(nil)),
+	)
+	err := parser.ParseArgs(&cfg, nil)
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if cfg.Host != "file-host" {
+		t.Errorf("Host = %s, want file-host", cfg.Host)
+	}
+	if cfg.Port != 9000 {
+		t.Errorf("Port = %d, want 9000", cfg.Port)
+	}
+	if !cfg.Debug {
+		t.Errorf("Debug = false, want true")
+	}
+	if cfg.Timeout != time.Minute {
+		t.Errorf("Timeout = %v, want 1m", cfg.Timeout)
+	}
+	if !slicesEqual(cfg.Tags, []string{"a", "b"}) {
+		t.Errorf("Tags = %v, want [a b]", cfg.Tags)
+	}
+	if cfg.Server.TLS.Port != 8443 {
+		t.Errorf("Server.TLS.Port = %d, want 8443", cfg.Server.TLS.Port)
+	}
+	if cfg.Server.TLS.Cert != "/etc/cert.pem" {
+		t.Errorf("Server.TLS.Cert = %s, want /etc/cert.pem", cfg.Server.TLS.Cert)
+	}
+}
+
+func TestYAMLConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+host: file-host
+port: 9000
+debug: true
+timeout: 1m
+tags:
+  - a
+  - b
+server:
+  tls:
+    port: 8443
+    certificate: /etc/cert.pem
+`)
+
+	var cfg FileConfig
+	parser := configlib.NewParser(
+		configlib.WithConfigFile(path),
+		configlib.WithEnvLookup(envLookup(nil)),
+	)
+	err := parser.ParseArgs(&cfg, nil)
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if cfg.Host != "file-host" {
+		t.Errorf("Host = %s, want file-host", cfg.Host)
+	}
+	if cfg.Port != 9000 {
+		t.Errorf("Port = %d, want 9000", cfg.Port)
+	}
+	if !cfg.Debug {
+		t.Errorf("Debug = false, want true")
+	}
+	if cfg.Timeout != time.Minute {
+		t.Errorf("Timeout = %v, want 1m", cfg.Timeout)
+	}
+	if !slicesEqual(cfg.Tags, []string{"a", "b"}) {
+		t.Errorf("Tags = %v, want [a b]", cfg.Tags)
+	}
+	if cfg.Server.TLS.Port != 8443 {
+		t.Errorf("Server.TLS.Port = %d, want 8443", cfg.Server.TLS.Port)
+	}
+	if cfg.Server.TLS.Cert != "/etc/cert.pem" {
+		t.Errorf("Server.TLS.Cert = %s, want /etc/cert.pem", cfg.Server.TLS.Cert)
+	}
+}
+
+func TestYAMLConfigFileNonStringKeys(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+codes:
+  404: not found
+  500: server error
+server:
+  ports:
+    80: http
+    443: https
+`)
+
+	var cfg struct {
+		Codes  map[int]string
+		Server struct {
+			Ports map[uint16]string
+		}
+	}
+	parser := configlib.NewParser(
+		configlib.WithConfigFile(path),
+		configlib.WithEnvLookup(envLookup(nil)),
+	)
+	if err := parser.ParseArgs(&cfg, nil); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if len(cfg.Codes) != 2 || cfg.Codes[404] != "not found" || cfg.Codes[500] != "server error" {
+		t.Errorf("Codes = %v, want map[404:not found 500:server error]", cfg.Codes)
+	}
+	if len(cfg.Server.Ports) != 2 || cfg.Server.Ports[80] != "http" || cfg.Server.Ports[443] != "https" {
+		t.Errorf("Server.Ports = %v, want map[80:http 443:https]", cfg.Server.Ports)
+	}
+}
+
+func TestTOMLAndINIConfigFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		body string
+	}{
+		{
+			name: "toml",
+			file: "config.toml",
+			body: `
+host = "file-host"
+port = 9000
+debug = true
+timeout = "1m"
+tags = ["a", "b"]
+
+[server.tls]
+port = 8443
+certificate = "/etc/cert.pem"
+`,
+		},
+		{
+			name: "ini",
+			file: "config.ini",
+			body: `
+; top-level keys
+host = file-host
+port = 9000
+debug = true
+timeout = 1m
+tags = a,b
+
+[server.tls]
+# nested section
+port = 8443
+certificate = "/etc/cert.pem"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tt.file, tt.body)
+
+			var cfg FileConfig
+			parser := configlib.NewParser(
+				configlib.WithConfigFile(path),
+				configlib.WithEnvLookup(envLookup(nil)),
+			)
+			err := parser.ParseArgs(&cfg, nil)
+			if err != nil {
+				t.Fatalf("ParseArgs failed: %v", err)
+			}
+
+			if cfg.Host != "file-host" {
+				t.Errorf("Host = %s, want file-host", cfg.Host)
+			}
+			if cfg.Port != 9000 {
+				t.Errorf("Port = %d, want 9000", cfg.Port)
+			}
+			if !cfg.Debug {
+				t.Errorf("Debug = false, want true")
+			}
+			if cfg.Timeout != time.Minute {
+				t.Errorf("Timeout = %v, want 1m", cfg.Timeout)
+			}
+			if !slicesEqual(cfg.Tags, []string{"a", "b"}) {
+				t.Errorf("Tags = %v, want [a b]", cfg.Tags)
+			}
+			if cfg.Server.TLS.Port != 8443 {
+				t.Errorf("Server.TLS.Port = %d, want 8443", cfg.Server.TLS.Port)
+			}
+			if cfg.Server.TLS.Cert != "/etc/cert.pem" {
+				t.Errorf("Server.TLS.Cert = %s, want /etc/cert.pem", cfg.Server.TLS.Cert)
+			}
+		})
+	}
+}
+
+func TestINIInlineComments(t *testing.T) {
+	path := writeConfigFile(t, "config.ini", `
+host = file-host ; public name
+port = 9000	# tab before comment
+tags = a#1,b;2
+[server.tls]
+certificate = "/etc/cert;1.pem" ; quoted
+`)
+
+	var cfg FileConfig
+	parser := configlib.NewParser(
+		configlib.WithConfigFile(path),
+		configlib.WithEnvLookup(envLookup(nil)),
+	)
+	if err := parser.ParseArgs(&cfg, nil); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if cfg.Host != "file-host" {
+		t.Errorf("Host = %q, want %q", cfg.Host, "file-host")
+	}
+	if cfg.Port != 9000 {
+		t.Errorf("Port = %d, want 9000", cfg.Port)
+	}
+	if !slicesEqual(cfg.Tags, []string{"a#1", "b;2"}) {
+		t.Errorf("Tags = %v, want [a#1 b;2]", cfg.Tags)
+	}
+	if cfg.Server.TLS.Cert != "/etc/cert;1.pem" {
+		t.Errorf("Server.TLS.Cert = %q, want %q", cfg.Server.TLS.Cert, "/etc/cert;1.pem")
+	}
+}
+
+func TestConfigFileTypeErrorsMatchAcrossSources(t *testing.T) {
+	envErr := configlib.NewParser(
+		configlib.WithEnvLookup(envLookup(map[string]string{"PORT": "abc"})),
+	).ParseArgs(&FileConfig{}, nil)
+	if envErr == nil {
+		t.Fatal("Expected error for invalid env value, got nil")
+	}
+
+	files := map[string]string{
+		"config.json": `{"port": "abc"}`,
+		"config.yaml": `port: abc`,
+		"config.toml": `port = "abc"`,
+		"config.ini":  `port = abc`,
+	}
+	for name, body := range files {
+		t.Run(name, func(t *testing.T) {
+			path := writeConfigFile(t, name, body)
+			err := configlib.NewParser(
+				configlib.WithConfigFile(path),
+				configlib.WithEnvLookup(envLookup(nil)),
+			).ParseArgs(&FileConfig{}, nil)
+			if err == nil || err.Error() != envErr.Error() {
+				t.Errorf("ParseArgs() error = %v, want %v", err, envErr)
+			}
+		})
+	}
+}
+
+func TestWithFileDecoder(t *testing.T) {
+	// A minimal "key: value" format with one entry per line
+	decode := func(content []byte) (map[string]any, error) {
+		data := make(map[string]any)
+		for _, line := range strings.Split(string(content), "\n") {
+			if key, val, ok := strings.Cut(line, ":"); ok {
+				data[strings.TrimSpace(key)] = strings.TrimSpace(val)
+			}
+		}
+		return data, nil
+	}
+
+	type Config struct {
+		Host string `conf:"hostname"`
+		Port int
+	}
+
+	path := writeConfigFile(t, "app.conf", "hostname: file-host\nport: 9000\n")
+
+	var cfg Config
+	parser := configlib.NewParser(
+		configlib.WithFileDecoder(".conf", "conf", decode),
+		configlib.WithConfigFile(path),
+		configlib.WithEnvLookup(envLookup(nil)),
+	)
+	err := parser.ParseArgs(&cfg, nil)
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if cfg.Host != "file-host" {
+		t.Errorf("Host = %s, want file-host", cfg.Host)
+	}
+	if cfg.Port != 9000 {
+		t.Errorf("Port = %d, want 9000", cfg.Port)
+	}
+}
+
+func TestConfigFileUnknownKeys(t *testing.T) {
+	path := writeConfigFile(t, "config.yml", `
+port: 9000
+hots: typo
+server:
+  tls:
+    prot: 8443
+`)
+
+	var cfg FileConfig
+	parser := configlib.NewParser(
+		configlib.WithConfigFile(path),
+		configlib.WithEnvLookup(envLookup(nil)),
+	)
+	err := parser.ParseArgs(&cfg, []string{"--port", "9001"})
+	if err == nil {
+		t.Fatal("Expected error for unknown keys, got nil")
+	}
+
+	// Keys shadowed by higher-priority sources are still known
+	expected := "unknown keys in config file " + path + ": hots, server.tls.prot"
+	if err.Error() != expected {
+		t.Errorf("ParseArgs() error = %q, want %q", err.Error(), expected)
+	}
+}
+
+func TestConfigFileEmptySections(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "yaml null section",
+			file: "config.yaml",
+			content: `
+port: 9000
+server:
+  tls:
+    # port: 8443
+`,
+		},
+		{
+			name: "yaml empty section",
+			file: "config.yaml",
+			content: `
+server: {}
+`,
+		},
+		{
+			name: "ini section with only comments",
+			file: "config.ini",
+			content: `
+port = 9000
+
+[server.tls]
+; port = 8443
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tt.file, tt.content)
+
+			var cfg FileConfig
+			parser := configlib.NewParser(
+				configlib.WithConfigFile(path),
+				configlib.WithEnvLookup(envLookup(nil)),
+			)
+			if err := parser.ParseArgs(&cfg, nil); err != nil {
+				t.Fatalf("ParseArgs failed: %v", err)
+			}
+			if cfg.Server.TLS.Port != 443 {
+				t.Errorf("Server.TLS.Port = %d, want default 443", cfg.Server.TLS.Port)
+			}
+		})
+	}
+}
+
+func TestConfigFileEmptyUnknownSection(t *testing.T) {
+	path := writeConfigFile(t, "config.ini", `
+[metrics]
+; enabled = true
+`)
+
+	var cfg FileConfig
+	parser := configlib.NewParser(
+		configlib.WithConfigFile(path),
+		configlib.WithEnvLookup(envLookup(nil)),
+	)
+	err := parser.ParseArgs(&cfg, nil)
+	expected := "unknown keys in config file " + path + ": metrics"
+	if err == nil || err.Error() != expected {
+		t.Errorf("ParseArgs() error = %v, want %q", err, expected)
+	}
+}
+
+func TestConfigFilePrecedence(t *testing.T) {
+	base := writeConfigFile(t, "base.json", `{"host": "base-host", "port": 9000, "debug": true}`)
+	override := writeConfigFile(t, "override.json", `{"port": 9001}`)
+
+	var cfg FileConfig
+	parser := configlib.NewParser(
+		configlib.WithConfigFile(base),
+		configlib.WithConfigFile(override),
+		configlib.WithEnvLookup(envLookup(map[string]string{"HOST": "env-host"})),
+	)
+	err := parser.ParseArgs(&cfg, []string{"--debug=false"})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	// Env overrides file
+	if cfg.Host != "env-host" {
+		t.Errorf("Host = %s, want env-host", cfg.Host)
+	}
+	// Later file overrides earlier file
+	if cfg.Port != 9001 {
+		t.Errorf("Port = %d, want 9001", cfg.Port)
+	}
+	// CLI overrides file
+	if cfg.Debug {
+		t.Errorf("Debug = true, want false")
+	}
+	// Default used when no file sets the value
+	if cfg.Server.TLS.Port != 443 {
+		t.Errorf("Server.TLS.Port = %d, want 443", cfg.Server.TLS.Port)
+	}
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		body   string
+		errMsg string
+	}{
+		{
+			name:   "unsupported format",
+			file:   "config.xml",
+			body:   "<config/>",
+			errMsg: "unsupported config file format",
+		},
+		{
+			name:   "invalid json",
+			file:   "config.json",
+			body:   `{"port": `,
+			errMsg: "error parsing config file",
+		},
+		{
+			name:   "trailing json",
+			file:   "config.json",
+			body:   `{"port": 9000} {"port": 9001}`,
+			errMsg: "invalid data after top-level value",
+		},
+		{
+			name:   "trailing json brace",
+			file:   "config.json",
+			body:   `{"port": 9000}}`,
+			errMsg: "invalid data after top-level value",
+		},
+		{
+			name:   "invalid yaml",
+			file:   "config.yaml",
+			body:   "port: [",
+			errMsg: "error parsing config file",
+		},
+		{
+			name:   "invalid toml",
+			file:   "config.toml",
+			body:   "port = ",
+			errMsg: "error parsing config file",
+		},
+		{
+			name:   "invalid ini",
+			file:   "config.ini",
+			body:   "[server",
+			errMsg: "error parsing config file",
+		},
+		{
+			name:   "invalid value",
+			file:   "config.json",
+			body:   `{"port": "not-a-number"}`,
+			errMsg: "error setting field Port",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tt.file, tt.body)
+
+			var cfg FileConfig
+			parser := configlib.NewParser(
+				configlib.WithConfigFile(path),
+				configlib.WithEnvLookup(envLookup(nil)),
+			)
+			err := parser.ParseArgs(&cfg, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		var cfg FileConfig
+		parser := configlib.NewParser(configlib.WithConfigFile(filepath.Join(t.TempDir(), "missing.json")))
+		err := parser.ParseArgs(&cfg, nil)
+		if err == nil || !strings.Contains(err.Error(), "error reading config file") {
+			t.Errorf("ParseArgs() error = %v, want error reading config file", err)
+		}
+	})
+}
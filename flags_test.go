@@ -3,6 +3,8 @@ package configlib_test
 import (
 	"flag"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -740,6 +742,83 @@ func TestDurationValidation(t *testing.T) {
 	}
 }
 
+func TestRepeatedSliceFlags(t *testing.T) {
+	type Config struct {
+		Tags     []string `env:"TAGS" flag:"tag,t" default:"default"`
+		Filters  []string `env:"FILTERS" flag:"filter" repeat:"append"`
+		Replaced []string `env:"REPLACED" flag:"replaced" repeat:"replace"`
+		Ports    []int    `env:"PORTS" flag:"port"`
+	}
+
+	tests := []struct {
+		name     string
+		envVars  map[string]string
+		cliArgs  []string
+		expected Config
+	}{
+		{
+			name:    "env and default used without flags",
+			envVars: map[string]string{"FILTERS": "a,b"},
+			expected: Config{
+				Tags:    []string{"default"},
+				Filters: []string{"a", "b"},
+			},
+		},
+		{
+			name:    "split mode appends comma-split occurrences",
+			envVars: map[string]string{"TAGS": "env"},
+			cliArgs: []string{"--tag", "a", "-t", "b,c", "--port", "80", "--port", "443"},
+			expected: Config{
+				Tags:  []string{"a", "b", "c"},
+				Ports: []int{80, 443},
+			},
+		},
+		{
+			name:    "append mode keeps occurrences whole",
+			cliArgs: []string{"--filter", "name=a,b", "--filter", "c"},
+			expected: Config{
+				Tags:    []string{"default"},
+				Filters: []string{"name=a,b", "c"},
+			},
+		},
+		{
+			name:    "replace mode keeps last occurrence",
+			cliArgs: []string{"--replaced", "a,b", "--replaced", "c,d"},
+			expected: Config{
+				Tags:     []string{"default"},
+				Replaced: []string{"c", "d"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			parser := configlib.NewParser(configlib.WithEnvLookup(envLookup(tt.envVars)))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err != nil {
+				t.Fatalf("ParseArgs failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(cfg, tt.expected) {
+				t.Errorf("ParseArgs() got = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInvalidRepeatTag(t *testing.T) {
+	type Config struct {
+		Tags []string `flag:"tag" repeat:"merge"`
+	}
+
+	var cfg Config
+	err := configlib.NewParser().ParseArgs(&cfg, nil)
+	if err == nil || !strings.Contains(err.Error(), `invalid repeat tag "merge" on field Tags`) {
+		t.Errorf("ParseArgs() error = %v, want invalid repeat tag error", err)
+	}
+}
+
 type IntegerTypesConfig struct {
 	Int8Val   int8   `env:"INT8_VAL" flag:"int8" default:"42" desc:"8-bit signed integer"`
 	Int16Val  int16  `env:"INT16_VAL" flag:"int16" default:"1000" desc:"16-bit signed integer"`
@@ -0,0 +1,126 @@
+package configlib
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Kinds of field groups, named after their struct tags
+const (
+	groupExclusive  = "exclusive"
+	groupAtLeastOne = "atleastone"
+)
+
+// fieldGroup is a set of fields sharing a group name in an exclusive or
+// atleastone tag, holding their indexes in Parser.fields
+type fieldGroup struct {
+	name   string
+	kind   string
+	fields []int
+}
+
+// fieldGroups returns the groups declared by the exclusive and atleastone
+// tags, in the order they first appear
+func (p *Parser) fieldGroups() []fieldGroup {
+	var groups []fieldGroup
+	for i, field := range p.fields {
+		for _, tag := range []struct{ kind, names string }{
+			{groupExclusive, field.Exclusive},
+			{groupAtLeastOne, field.AtLeastOne},
+		} {
+			if tag.names == "" {
+				continue
+			}
+			for _, name := range strings.Split(tag.names, ",") {
+				name = strings.TrimSpace(name)
+				if name == "" {
+					continue
+				}
+				idx := slices.IndexFunc(groups, func(g fieldGroup) bool {
+					return g.name == name && g.kind == tag.kind
+				})
+				if idx < 0 {
+					groups = append(groups, fieldGroup{name: name, kind: tag.kind})
+					idx = len(groups) - 1
+				}
+				groups[idx].fields = append(groups[idx].fields, i)
+			}
+		}
+	}
+	return groups
+}
+
+// checkGroups returns a GroupError for each group whose constraint is broken
+// given which fields were explicitly set. Values from default tags do not
+// count, so a defaulted field never conflicts with one that is set.
+func (p *Parser) checkGroups(explicit []bool) []error {
+	var problems []error
+	for _, group := range p.fieldGroups() {
+		var all, set []string
+		for _, i := range group.fields {
+			all = append(all, p.fields[i].FieldPath)
+			if explicit[i] {
+				set = append(set, p.fields[i].FieldPath)
+			}
+		}
+
+		if (group.kind == groupExclusive && len(set) > 1) || (group.kind == groupAtLeastOne && len(set) == 0) {
+			problems = append(problems, &GroupError{
+				Group:     group.name,
+				Exclusive: group.kind == groupExclusive,
+				Fields:    all,
+				Set:       set,
+			})
+		}
+	}
+	return problems
+}
+
+// printConstraints prints the field groups for help output. A group that is
+// both exclusive and atleastone over the same fields is shown as exactly one.
+func (p *Parser) printConstraints() {
+	groups := p.fieldGroups()
+	if len(groups) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("Constraints:")
+	for i, group := range groups {
+		rule := "at most one of"
+		if group.kind == groupAtLeastOne {
+			rule = "at least one of"
+		}
+
+		// Merge with the matching group of the other kind
+		other := slices.IndexFunc(groups, func(g fieldGroup) bool {
+			return g.name == group.name && g.kind != group.kind && slices.Equal(g.fields, group.fields)
+		})
+		if other >= 0 {
+			if other < i {
+				continue
+			}
+			rule = "exactly one of"
+		}
+
+		names := make([]string, 0, len(group.fields))
+		for _, idx := range group.fields {
+			names = append(names, helpName(p.fields[idx].Field))
+		}
+		fmt.Printf("  %s: %s %s\n", group.name, rule, strings.Join(names, ", "))
+	}
+}
+
+// helpName returns how help output refers to field: by its flag, or by its
+// path if it has none
+func helpName(field Field) string {
+	switch {
+	case len(field.CliName) == 1:
+		return "-" + field.CliName
+	case field.CliName != "":
+		return "--" + field.CliName
+	default:
+		return field.FieldPath
+	}
+}
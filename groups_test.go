@@ -0,0 +1,131 @@
+package configlib_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bherbruck/configlib"
+)
+
+type GroupConfig struct {
+	Token     string `env:"TOKEN" flag:"token" exclusive:"auth" atleastone:"auth"`
+	TokenFile string `env:"TOKEN_FILE" flag:"token-file" exclusive:"auth" atleastone:"auth"`
+	JSON      bool   `env:"JSON" flag:"json" exclusive:"format"`
+	YAML      bool   `env:"YAML" flag:"yaml,y" exclusive:"format"`
+	Region    string `env:"REGION" atleastone:"location"`
+	Endpoint  string `env:"ENDPOINT" flag:"endpoint" atleastone:"location"`
+}
+
+func TestFieldGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+	}{
+		{
+			name:    "token from flag",
+			environ: []string{"REGION=eu-west-1"},
+			cliArgs: []string{"--token", "secret"},
+		},
+		{
+			name:    "token file from env with one format",
+			environ: []string{"TOKEN_FILE=/run/token", "ENDPOINT=https://api.example.com"},
+			cliArgs: []string{"-y"},
+		},
+		{
+			name:    "every location field",
+			environ: []string{"TOKEN=secret", "REGION=eu-west-1", "ENDPOINT=https://api.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg GroupConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			if err := parser.ParseArgs(&cfg, tt.cliArgs); err != nil {
+				t.Errorf("ParseArgs() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFieldGroupErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+		errMsgs []string
+	}{
+		{
+			name:    "mutually exclusive across sources",
+			environ: []string{"TOKEN_FILE=/run/token", "REGION=eu-west-1"},
+			cliArgs: []string{"--token", "secret"},
+			errMsgs: []string{"group auth: at most one of Token, TokenFile may be set, got Token, TokenFile"},
+		},
+		{
+			name:    "none of a required group",
+			environ: []string{"REGION=eu-west-1"},
+			errMsgs: []string{"group auth: at least one of Token, TokenFile is required"},
+		},
+		{
+			name:    "exclusive bool flags",
+			environ: []string{"TOKEN=secret", "REGION=eu-west-1"},
+			cliArgs: []string{"--json", "-y"},
+			errMsgs: []string{"group format: at most one of JSON, YAML may be set, got JSON, YAML"},
+		},
+		{
+			name: "all violations reported",
+			errMsgs: []string{
+				"constraint violations:\n" +
+					"  - group auth: at least one of Token, TokenFile is required\n" +
+					"  - group location: at least one of Region, Endpoint is required",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg GroupConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil {
+				t.Fatal("ParseArgs() expected error, got nil")
+			}
+			for _, errMsg := range tt.errMsgs {
+				if !strings.Contains(err.Error(), errMsg) {
+					t.Errorf("ParseArgs() error = %v, want error containing %q", err, errMsg)
+				}
+			}
+		})
+	}
+}
+
+type DefaultGroupConfig struct {
+	Host   string `flag:"host" default:"localhost" exclusive:"target"`
+	Socket string `flag:"socket" exclusive:"target"`
+	Format string `flag:"format" default:"text" atleastone:"output"`
+	Output string `flag:"output" atleastone:"output"`
+}
+
+func TestFieldGroupsIgnoreDefaults(t *testing.T) {
+	var cfg DefaultGroupConfig
+	parser := configlib.NewParser(configlib.WithEnviron(nil))
+	err := parser.ParseArgs(&cfg, []string{"--socket", "/run/app.sock", "--output", "out.txt"})
+	if err != nil {
+		t.Fatalf("ParseArgs() unexpected error: %v", err)
+	}
+	if cfg.Host != "localhost" {
+		t.Errorf("Host = %q, want default %q", cfg.Host, "localhost")
+	}
+
+	cfg = DefaultGroupConfig{}
+	parser = configlib.NewParser(configlib.WithEnviron(nil))
+	err = parser.ParseArgs(&cfg, nil)
+	want := "group output: at least one of Format, Output is required"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("ParseArgs() error = %v, want error containing %q", err, want)
+	}
+	if err != nil && strings.Contains(err.Error(), "group target") {
+		t.Errorf("ParseArgs() error = %v, want no error for group target", err)
+	}
+}
@@ -1,6 +1,7 @@
 package configlib_test
 
 import (
+	"errors"
 	"os"
 	"strings"
 	"testing"
@@ -40,9 +41,16 @@ func TestHelpWithMultipleFlags(t *testing.T) {
 }
 
 func TestHelpFlag(t *testing.T) {
-	// This test is skipped because we can't easily mock os.Exit
-	// The help functionality is tested through other means
-	t.Skip("Cannot mock os.Exit in Go")
+	for _, arg := range []string{"--help", "-h"} {
+		t.Run(arg, func(t *testing.T) {
+			var cfg SimpleConfig
+			parser := configlib.NewParser()
+			err := parser.ParseArgs(&cfg, []string{arg})
+			if !errors.Is(err, configlib.ErrHelp) {
+				t.Errorf("ParseArgs(%s) error = %v, want ErrHelp", arg, err)
+			}
+		})
+	}
 }
 
 func TestHelpOutput(t *testing.T) {
@@ -157,3 +165,91 @@ func ExampleParser_PrintHelp() {
 	//   --debug            Enable debug mode
 	//   -h, --help         Show this help message
 }
+
+func TestHelpByteSizeDefaults(t *testing.T) {
+	type Config struct {
+		Cache  configlib.ByteSize `flag:"cache" default:"67108864" desc:"Cache size"`
+		Buffer int                `flag:"buffer" unit:"bytes" default:"1.5gb" desc:"Buffer size"`
+	}
+
+	var cfg Config
+	parser := configlib.NewParser(configlib.WithEnviron(nil))
+	if err := parser.ParseArgs(&cfg, nil); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	helpStr := parser.GetHelp()
+	for _, expected := range []string{"Cache size (default: 64MiB)", "Buffer size (default: 1.5GB)"} {
+		if !strings.Contains(helpStr, expected) {
+			t.Errorf("Help output missing expected string: %s", expected)
+		}
+	}
+}
+
+func TestHelpOneOf(t *testing.T) {
+	var cfg OneOfConfig
+	parser := configlib.NewParser(configlib.WithEnviron(nil))
+	if err := parser.ParseArgs(&cfg, nil); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	helpStr := parser.GetHelp()
+	expected := "Log level (default: info) (one of: debug, info, warn, error)"
+	if !strings.Contains(helpStr, expected) {
+		t.Errorf("Help output missing expected string: %s", expected)
+	}
+}
+
+func TestHelpPattern(t *testing.T) {
+	type Config struct {
+		Bucket string `flag:"bucket" pattern:"[a-z0-9-]+" desc:"Bucket name"`
+	}
+
+	var cfg Config
+	parser := configlib.NewParser(configlib.WithEnviron(nil))
+	if err := parser.ParseArgs(&cfg, nil); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	helpStr := parser.GetHelp()
+	expected := "Bucket name (pattern: [a-z0-9-]+)"
+	if !strings.Contains(helpStr, expected) {
+		t.Errorf("Help output missing expected string: %s", expected)
+	}
+}
+
+func TestHelpConditionalRequirements(t *testing.T) {
+	var cfg ConditionalConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{"LOG_FILE=app.log"}))
+	if err := parser.ParseArgs(&cfg, nil); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	helpStr := parser.GetHelp()
+	for _, expected := range []string{
+		"TLS.CertFile [required if Mode=tls]",
+		"TLS.KeyFile [required with TLS.CertFile]",
+		"LogFile [required unless Debug=true]",
+	} {
+		if !strings.Contains(helpStr, expected) {
+			t.Errorf("Help output missing expected string: %s", expected)
+		}
+	}
+}
+
+func TestHelpConstraints(t *testing.T) {
+	var cfg GroupConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{"TOKEN=secret", "REGION=eu-west-1"}))
+	if err := parser.ParseArgs(&cfg, nil); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	helpStr := parser.GetHelp()
+	expected := "Constraints:\n" +
+		"  auth: exactly one of --token, --token-file\n" +
+		"  format: at most one of --json, --yaml\n" +
+		"  location: at least one of --region, --endpoint\n"
+	if !strings.Contains(helpStr, expected) {
+		t.Errorf("Help output missing constraints section %q, got:\n%s", expected, helpStr)
+	}
+}
@@ -235,3 +235,70 @@ func TestCombinedOptions(t *testing.T) {
 		t.Errorf("Field4: expected 'default4', got '%s'", cfg.Field4)
 	}
 }
+
+// envLookup returns an environment lookup function backed by a map
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	}
+}
+
+func TestParseArgsWithEnvLookup(t *testing.T) {
+	tests := []struct {
+		name     string
+		envVars  map[string]string
+		cliArgs  []string
+		expected SimpleConfig
+	}{
+		{
+			name:    "env lookup",
+			envVars: map[string]string{"PORT": "9000", "REQUIRED": "from-env"},
+			expected: SimpleConfig{
+				Host:     "localhost",
+				Port:     9000,
+				Required: "from-env",
+			},
+		},
+		{
+			name:    "args override env lookup",
+			envVars: map[string]string{"HOST": "env-host", "REQUIRED": "from-env"},
+			cliArgs: []string{"--host", "cli-host", "--debug"},
+			expected: SimpleConfig{
+				Host:     "cli-host",
+				Port:     8080,
+				Debug:    true,
+				Required: "from-env",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cfg SimpleConfig
+			parser := configlib.NewParser(configlib.WithEnvLookup(envLookup(tt.envVars)))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err != nil {
+				t.Fatalf("ParseArgs failed: %v", err)
+			}
+
+			if cfg != tt.expected {
+				t.Errorf("ParseArgs() got = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseArgsIgnoresProcessEnv(t *testing.T) {
+	os.Setenv("REQUIRED", "from-process")
+	defer os.Unsetenv("REQUIRED")
+
+	var cfg SimpleConfig
+	parser := configlib.NewParser(configlib.WithEnvLookup(envLookup(nil)))
+	err := parser.ParseArgs(&cfg, []string{})
+	if err == nil {
+		t.Fatalf("Expected missing required field error, got config %+v", cfg)
+	}
+}
@@ -0,0 +1,47 @@
+package configlib
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// redacted replaces the values of fields tagged secret:"true" in provenance
+const redacted = "***"
+
+// Provenance records which source supplied a field's final value
+type Provenance struct {
+	FieldPath string
+	Source    string   // Name of the source that supplied the value, empty if none did
+	Value     string   // Value as supplied by Source, or *** for secret fields
+	Shadowed  []string // Names of lower-priority sources that also had a value
+}
+
+// Provenance returns where each field's value came from, in field order.
+// It is populated by Parse.
+func (p *Parser) Provenance() []Provenance {
+	return append([]Provenance(nil), p.provenance...)
+}
+
+// Explain returns a table showing where each field's value came from
+func (p *Parser) Explain() string {
+	var buf strings.Builder
+
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FIELD\tSOURCE\tVALUE\tSHADOWED")
+	for _, prov := range p.provenance {
+		source := prov.Source
+		if source == "" {
+			source = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", prov.FieldPath, source, prov.Value, strings.Join(prov.Shadowed, ", "))
+	}
+	w.Flush()
+
+	return buf.String()
+}
+
+// PrintExplain prints the table returned by Explain
+func (p *Parser) PrintExplain() {
+	fmt.Print(p.Explain())
+}
@@ -0,0 +1,136 @@
+package configlib_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bherbruck/configlib"
+)
+
+func TestProvenance(t *testing.T) {
+	type Config struct {
+		Host  string `env:"HOST" flag:"host" default:"localhost"`
+		Port  int    `env:"PORT" flag:"port" default:"8080"`
+		Debug bool   `env:"DEBUG" flag:"debug"`
+		Name  string `env:"NAME" flag:"name"`
+	}
+
+	var cfg Config
+	parser := configlib.NewParser(configlib.WithEnvLookup(envLookup(map[string]string{
+		"HOST":  "env-host",
+		"DEBUG": "true",
+	})))
+	err := parser.ParseArgs(&cfg, []string{"--host", "cli-host"})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	expected := []configlib.Provenance{
+		{FieldPath: "Host", Source: "flag", Value: "cli-host", Shadowed: []string{"env", "default"}},
+		{FieldPath: "Port", Source: "default", Value: "8080"},
+		{FieldPath: "Debug", Source: "env", Value: "true"},
+		{FieldPath: "Name"},
+	}
+	if got := parser.Provenance(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Provenance() = %+v, want %+v", got, expected)
+	}
+}
+
+func TestProvenanceConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{"port": 9000}`)
+
+	var cfg SimpleConfig
+	parser := configlib.NewParser(
+		configlib.WithConfigFile(path),
+		configlib.WithEnvLookup(envLookup(map[string]string{"REQUIRED": "x"})),
+	)
+	err := parser.ParseArgs(&cfg, nil)
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	for _, prov := range parser.Provenance() {
+		if prov.FieldPath != "Port" {
+			continue
+		}
+		if prov.Source != "file "+path {
+			t.Errorf("Port source = %s, want file %s", prov.Source, path)
+		}
+		if !reflect.DeepEqual(prov.Shadowed, []string{"default"}) {
+			t.Errorf("Port shadowed = %v, want [default]", prov.Shadowed)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	var cfg SimpleConfig
+	parser := configlib.NewParser(configlib.WithEnvLookup(envLookup(map[string]string{
+		"PORT":     "9000",
+		"REQUIRED": "secret",
+	})))
+	err := parser.ParseArgs(&cfg, []string{"--port", "9001"})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(parser.Explain(), "\n") {
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+
+	expected := []string{
+		"FIELD     SOURCE   VALUE      SHADOWED",
+		"Host      default  localhost",
+		"Port      flag     9001       env, default",
+		"Debug     default  false",
+		"Required  env      secret",
+		"",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Explain() =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestExplainRedactsSecrets(t *testing.T) {
+	var cfg struct {
+		User     string `env:"USER"`
+		Password string `env:"PASSWORD" secret:"true"`
+		Token    string `env:"TOKEN" secret:"true"`
+	}
+	parser := configlib.NewParser(configlib.WithEnvLookup(envLookup(map[string]string{
+		"USER":     "admin",
+		"PASSWORD": "hunter2",
+	})))
+	if err := parser.ParseArgs(&cfg, nil); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+	if cfg.Password != "hunter2" {
+		t.Errorf("Password = %q, want %q", cfg.Password, "hunter2")
+	}
+
+	explain := parser.Explain()
+	if strings.Contains(explain, "hunter2") {
+		t.Errorf("Explain() = %q, want secret value redacted", explain)
+	}
+	var lines []string
+	for _, line := range strings.Split(explain, "\n") {
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	expected := []string{
+		"FIELD     SOURCE  VALUE  SHADOWED",
+		"User      env     admin",
+		"Password  env     ***",
+		"Token     -",
+		"",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Explain() =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(expected, "\n"))
+	}
+
+	for _, prov := range parser.Provenance() {
+		if prov.FieldPath == "Password" && prov.Value != "***" {
+			t.Errorf("Password provenance value = %q, want ***", prov.Value)
+		}
+	}
+}
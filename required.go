@@ -0,0 +1,119 @@
+package configlib
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// condition refers to another field by its index in Parser.fields, and for
+// required_if and required_unless to the value it is compared with
+type condition struct {
+	field int
+	value reflect.Value
+}
+
+// resolveRequirements resolves the fields referenced by the required_if,
+// required_unless and required_with tags once all fields are collected
+func (p *Parser) resolveRequirements() error {
+	paths := make(map[string]int, len(p.fields))
+	for i, field := range p.fields {
+		paths[field.FieldPath] = i
+	}
+
+	for i := range p.fields {
+		field := &p.fields[i]
+		tags := []struct {
+			name      string
+			tag       string
+			withValue bool
+			dst       *[]condition
+		}{
+			{"required_if", field.RequiredIf, true, &field.requiredIf},
+			{"required_unless", field.RequiredUnless, true, &field.requiredUnless},
+			{"required_with", field.RequiredWith, false, &field.requiredWith},
+		}
+		for _, t := range tags {
+			if t.tag == "" {
+				continue
+			}
+			refs, err := splitList(t.tag, ",")
+			if err != nil {
+				return fmt.Errorf("invalid %s tag %q on field %s: %v", t.name, t.tag, field.FieldPath, err)
+			}
+			for _, ref := range refs {
+				path, value, hasValue := strings.Cut(ref, "=")
+				if hasValue != t.withValue {
+					if t.withValue {
+						return fmt.Errorf("invalid %s tag %q on field %s: expected Field=value", t.name, t.tag, field.FieldPath)
+					}
+					return fmt.Errorf("invalid %s tag %q on field %s: expected field paths", t.name, t.tag, field.FieldPath)
+				}
+				idx, ok := paths[strings.TrimSpace(path)]
+				if !ok {
+					return fmt.Errorf("invalid %s tag on field %s: unknown field %s", t.name, field.FieldPath, strings.TrimSpace(path))
+				}
+
+				cond := condition{field: idx}
+				if t.withValue {
+					// Compare converted values, so that e.g. Debug=true
+					// also matches DEBUG=1
+					ref := p.fields[idx]
+					cond.value = reflect.New(p.valueType(ref.Type)).Elem()
+					if err := p.setValue(cond.value, value, ref.Field); err != nil {
+						return fmt.Errorf("invalid %s tag %q on field %s: %v", t.name, t.tag, field.FieldPath, err)
+					}
+				}
+				*t.dst = append(*t.dst, cond)
+			}
+		}
+	}
+	return nil
+}
+
+// isRequired reports whether field must have a value given which fields were
+// applied and which were explicitly set, and the reason for conditional
+// requirements. required_with ignores values from default tags.
+func (p *Parser) isRequired(field fieldInfo, applied, explicit []bool) (bool, string) {
+	if field.Required {
+		return true, ""
+	}
+
+	// required_if: all conditions hold
+	if len(field.requiredIf) > 0 && p.conditionsHold(field.requiredIf, applied) {
+		return true, "required if " + field.RequiredIf
+	}
+
+	// required_unless: not all conditions hold
+	if len(field.requiredUnless) > 0 && !p.conditionsHold(field.requiredUnless, applied) {
+		return true, "required unless " + field.RequiredUnless
+	}
+
+	// required_with: any referenced field was explicitly set
+	for _, cond := range field.requiredWith {
+		if explicit[cond.field] {
+			return true, "required with " + field.RequiredWith
+		}
+	}
+
+	return false, ""
+}
+
+// conditionsHold reports whether every referenced field was applied with the
+// value of its condition
+func (p *Parser) conditionsHold(conds []condition, applied []bool) bool {
+	for _, cond := range conds {
+		if !applied[cond.field] {
+			return false
+		}
+		ref := p.fields[cond.field]
+		val := ref.Value
+		if p.valueType(ref.Type) != ref.Type {
+			val = val.Elem()
+		}
+		if !reflect.DeepEqual(val.Interface(), cond.value.Interface()) {
+			return false
+		}
+	}
+	return true
+}
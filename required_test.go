@@ -0,0 +1,182 @@
+package configlib_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bherbruck/configlib"
+)
+
+type ConditionalConfig struct {
+	Mode  string `env:"MODE" flag:"mode" default:"plain"`
+	Debug bool   `env:"DEBUG" flag:"debug"`
+	TLS   struct {
+		CertFile string `env:"TLS_CERT_FILE" flag:"tls-cert-file" required_if:"Mode=tls"`
+		KeyFile  string `env:"TLS_KEY_FILE" flag:"tls-key-file" required_with:"TLS.CertFile"`
+	}
+	LogFile   string `env:"LOG_FILE" flag:"log-file" required_unless:"Debug=true"`
+	Backend   string `env:"BACKEND" flag:"backend"`
+	BackendID int    `env:"BACKEND_ID" flag:"backend-id" required_if:"Mode=tls,Backend=s3"`
+}
+
+func TestConditionalRequirements(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+	}{
+		{
+			name:    "optional subsystem disabled",
+			environ: []string{"LOG_FILE=app.log"},
+		},
+		{
+			name:    "tls enabled with its settings",
+			environ: []string{"MODE=tls", "TLS_CERT_FILE=cert.pem", "TLS_KEY_FILE=key.pem", "LOG_FILE=app.log"},
+		},
+		{
+			name:    "unless condition holds",
+			cliArgs: []string{"--debug"},
+		},
+		{
+			name:    "unless condition holds from converted value",
+			environ: []string{"DEBUG=1"},
+		},
+		{
+			name:    "only some required_if conditions hold",
+			environ: []string{"MODE=tls", "BACKEND=gcs", "TLS_CERT_FILE=cert.pem", "TLS_KEY_FILE=key.pem", "DEBUG=true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg ConditionalConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			if err := parser.ParseArgs(&cfg, tt.cliArgs); err != nil {
+				t.Errorf("ParseArgs() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestConditionalRequirementErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+		errMsgs []string
+	}{
+		{
+			name:    "required_if",
+			environ: []string{"MODE=tls", "DEBUG=true"},
+			errMsgs: []string{"TLS.CertFile (env: TLS_CERT_FILE, flag: --tls-cert-file) required if Mode=tls"},
+		},
+		{
+			name:    "required_with",
+			environ: []string{"TLS_CERT_FILE=cert.pem", "DEBUG=true"},
+			errMsgs: []string{"TLS.KeyFile (env: TLS_KEY_FILE, flag: --tls-key-file) required with TLS.CertFile"},
+		},
+		{
+			name:    "required_unless",
+			cliArgs: []string{"--debug=false"},
+			errMsgs: []string{"LogFile (env: LOG_FILE, flag: --log-file) required unless Debug=true"},
+		},
+		{
+			name:    "all required_if conditions hold",
+			cliArgs: []string{"--mode", "tls", "--backend", "s3", "--tls-cert-file", "c", "--tls-key-file", "k", "--debug"},
+			errMsgs: []string{"BackendID (env: BACKEND_ID, flag: --backend-id) required if Mode=tls,Backend=s3"},
+		},
+		{
+			name:    "reported in field order",
+			environ: []string{"MODE=tls"},
+			errMsgs: []string{
+				"missing required fields:\n" +
+					"  - TLS.CertFile (env: TLS_CERT_FILE, flag: --tls-cert-file) required if Mode=tls\n" +
+					"  - LogFile (env: LOG_FILE, flag: --log-file) required unless Debug=true",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg ConditionalConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil {
+				t.Fatal("ParseArgs() expected error, got nil")
+			}
+			for _, errMsg := range tt.errMsgs {
+				if !strings.Contains(err.Error(), errMsg) {
+					t.Errorf("ParseArgs() error = %v, want error containing %q", err, errMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidConditionalRequirementTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		config any
+		errMsg string
+	}{
+		{
+			name: "unknown field",
+			config: &struct {
+				Key string `required_with:"Cert"`
+			}{},
+			errMsg: "invalid required_with tag on field Key: unknown field Cert",
+		},
+		{
+			name: "missing value",
+			config: &struct {
+				Mode string
+				Key  string `required_if:"Mode"`
+			}{},
+			errMsg: `invalid required_if tag "Mode" on field Key: expected Field=value`,
+		},
+		{
+			name: "value for required_with",
+			config: &struct {
+				Cert string
+				Key  string `required_with:"Cert=x"`
+			}{},
+			errMsg: `invalid required_with tag "Cert=x" on field Key: expected field paths`,
+		},
+		{
+			name: "value of wrong type",
+			config: &struct {
+				Port int
+				Host string `required_unless:"Port=http"`
+			}{},
+			errMsg: `invalid required_unless tag "Port=http" on field Host`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := configlib.NewParser(configlib.WithEnviron(nil))
+			err := parser.ParseArgs(tt.config, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestRequiredWithIgnoresDefaults(t *testing.T) {
+	var cfg struct {
+		Proxy     string `flag:"proxy" default:"http://proxy:3128"`
+		ProxyAuth string `flag:"proxy-auth" required_with:"Proxy"`
+	}
+	parser := configlib.NewParser(configlib.WithEnviron(nil))
+	if err := parser.ParseArgs(&cfg, nil); err != nil {
+		t.Errorf("ParseArgs() unexpected error: %v", err)
+	}
+
+	parser = configlib.NewParser(configlib.WithEnviron(nil))
+	err := parser.ParseArgs(&cfg, []string{"--proxy", "http://other:3128"})
+	want := "ProxyAuth (env: PROXYAUTH, flag: --proxy-auth) required with Proxy"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("ParseArgs() error = %v, want error containing %q", err, want)
+	}
+}
@@ -0,0 +1,243 @@
+package configlib
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Source supplies configuration values for fields. Values are strings in the
+// same form accepted from environment variables.
+type Source interface {
+	// Name identifies the source in error messages and provenance reports
+	Name() string
+	// Lookup returns the value for field and whether it was found. Empty
+	// values are treated as not found.
+	Lookup(field Field) (string, bool)
+}
+
+// WithSources adds custom sources, in order of precedence. They are consulted
+// after CLI flags, environment variables and dotenv files, and before config
+// files and defaults.
+func WithSources(sources ...Source) Option {
+	return func(p *Parser) {
+		p.sources = append(p.sources, sources...)
+	}
+}
+
+// listSource is implemented by sources that can supply slice elements
+// directly instead of a comma-separated value
+type listSource interface {
+	lookupList(field Field) ([]string, bool)
+}
+
+// Repeat modes for slice flags given more than once
+const (
+	repeatSplit   = "split"   // Split each occurrence on commas and append (default)
+	repeatAppend  = "append"  // Append each occurrence as a single element
+	repeatReplace = "replace" // Split the last occurrence on commas
+)
+
+// sourceChain returns all sources in order of precedence
+func (p *Parser) sourceChain() []Source {
+	// Variables of other fields are not per-key variables of map fields
+	envNames := make(map[string]bool, len(p.fields))
+	for _, field := range p.fields {
+		if field.EnvName != "" {
+			envNames[field.EnvName] = true
+		}
+	}
+
+	chain := []Source{flagSource{p.flagValues}, envSource{p.lookupEnv, p.environ, envNames}}
+	if len(p.dotEnvPaths) > 0 {
+		chain = append(chain, dotEnvSource{p.dotEnv, envNames})
+	}
+	chain = append(chain, p.sources...)
+
+	// Later config files take precedence over earlier ones
+	for i := len(p.configFiles) - 1; i >= 0; i-- {
+		chain = append(chain, p.configFiles[i])
+	}
+
+	return append(chain, defaultSource{})
+}
+
+// flagSource looks up values set by CLI flags. Repeated map flags accumulate
+// their entries, repeated slice flags combine according to the field's repeat
+// mode, and for other fields the last occurrence wins.
+type flagSource struct {
+	values map[string][]string
+}
+
+func (s flagSource) Name() string { return "flag" }
+
+func (s flagSource) Lookup(field Field) (string, bool) {
+	if field.CliName == "" {
+		return "", false
+	}
+	vals := s.values[field.CliName]
+	if len(vals) == 0 {
+		return "", false
+	}
+	switch indirectType(field.Type).Kind() {
+	case reflect.Map:
+		return strings.Join(vals, field.Sep), true
+	case reflect.Slice:
+		elems, _ := s.lookupList(field)
+		quoted := make([]string, len(elems))
+		for i, elem := range elems {
+			quoted[i] = quoteElem(elem, field.Sep)
+		}
+		return strings.Join(quoted, field.Sep), true
+	}
+	return vals[len(vals)-1], true
+}
+
+func (s flagSource) lookupList(field Field) ([]string, bool) {
+	vals := s.values[field.CliName]
+	if field.CliName == "" || len(vals) == 0 {
+		return nil, false
+	}
+
+	// Occurrences were already validated by the flag handler, so splitting
+	// cannot fail here
+	switch field.Repeat {
+	case repeatAppend:
+		return vals, true
+	case repeatReplace:
+		elems, _ := splitList(vals[len(vals)-1], field.Sep)
+		return elems, true
+	default:
+		var elems []string
+		for _, val := range vals {
+			split, _ := splitList(val, field.Sep)
+			elems = append(elems, split...)
+		}
+		return elems, true
+	}
+}
+
+// envSource looks up values from environment variables
+type envSource struct {
+	lookup   func(string) (string, bool)
+	environ  func() []string // nil if the environment cannot be listed
+	envNames map[string]bool // Variables of all fields
+}
+
+func (s envSource) Name() string { return "env" }
+
+func (s envSource) Lookup(field Field) (string, bool) {
+	if field.EnvName == "" {
+		return "", false
+	}
+	val, ok := s.lookup(field.EnvName)
+	if indirectType(field.Type).Kind() != reflect.Map || s.environ == nil {
+		return val, ok
+	}
+
+	vars := make(map[string]string)
+	for _, entry := range s.environ() {
+		if key, val, ok := strings.Cut(entry, "="); ok {
+			vars[key] = val
+		}
+	}
+	return mapEnvValue(field, val, vars, s.envNames)
+}
+
+func (s envSource) lookupList(field Field) ([]string, bool) {
+	if field.EnvName == "" {
+		return nil, false
+	}
+	val, _ := s.lookup(field.EnvName)
+	return jsonArrayElems(val)
+}
+
+// dotEnvSource looks up values loaded from dotenv files
+type dotEnvSource struct {
+	vars     map[string]string
+	envNames map[string]bool // Variables of all fields
+}
+
+func (s dotEnvSource) Name() string { return "dotenv" }
+
+func (s dotEnvSource) Lookup(field Field) (string, bool) {
+	if field.EnvName == "" {
+		return "", false
+	}
+	val, ok := s.vars[field.EnvName]
+	if indirectType(field.Type).Kind() != reflect.Map {
+		return val, ok
+	}
+	return mapEnvValue(field, val, s.vars, s.envNames)
+}
+
+func (s dotEnvSource) lookupList(field Field) ([]string, bool) {
+	if field.EnvName == "" {
+		return nil, false
+	}
+	return jsonArrayElems(s.vars[field.EnvName])
+}
+
+// defaultSource looks up values from the `default` tag
+type defaultSource struct{}
+
+func (defaultSource) Name() string { return "default" }
+
+func (defaultSource) Lookup(field Field) (string, bool) {
+	return field.DefaultVal, field.DefaultVal != ""
+}
+
+// mapEnvValue combines the value of a map field's own variable (e.g. LABELS)
+// with per-key variables (e.g. LABELS_TEAM=infra becomes TEAM=infra), skipping
+// the variables in envNames that belong to other fields
+func mapEnvValue(field Field, val string, vars map[string]string, envNames map[string]bool) (string, bool) {
+	envName := field.EnvName
+	var keys []string
+	for key := range vars {
+		if strings.HasPrefix(key, envName+"_") && len(key) > len(envName)+1 && !envNames[key] {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return val, val != ""
+	}
+	sort.Strings(keys)
+
+	var entries []string
+	if val != "" {
+		entries = append(entries, val)
+	}
+	for _, key := range keys {
+		entries = append(entries, quoteElem(key[len(envName)+1:]+"="+vars[key], field.Sep))
+	}
+	return strings.Join(entries, field.Sep), true
+}
+
+// jsonArrayElems returns the elements of val if it is a JSON array of
+// strings, numbers or bools, e.g. ["a,b", "c"]
+func jsonArrayElems(val string) ([]string, bool) {
+	val = strings.TrimSpace(val)
+	if !strings.HasPrefix(val, "[") {
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(val))
+	decoder.UseNumber()
+	var arr []any
+	if err := decoder.Decode(&arr); err != nil || decoder.More() {
+		return nil, false
+	}
+
+	elems := make([]string, 0, len(arr))
+	for _, elem := range arr {
+		switch elem.(type) {
+		case string, json.Number, bool:
+			str, _ := fileValueString(elem, ",", "")
+			elems = append(elems, str)
+		default:
+			return nil, false
+		}
+	}
+	return elems, true
+}
@@ -0,0 +1,93 @@
+package configlib_test
+
+import (
+	"testing"
+
+	"github.com/bherbruck/configlib"
+)
+
+// mapSource is a Source backed by a map keyed by field path
+type mapSource struct {
+	name   string
+	values map[string]string
+}
+
+func (s mapSource) Name() string { return s.name }
+
+func (s mapSource) Lookup(field configlib.Field) (string, bool) {
+	val, ok := s.values[field.FieldPath]
+	return val, ok
+}
+
+func TestWithSources(t *testing.T) {
+	secrets := mapSource{name: "secrets", values: map[string]string{
+		"Required":        "from-secrets",
+		"Host":            "secrets-host",
+		"Server.TLS.Cert": "secret-cert",
+	}}
+	kv := mapSource{name: "kv", values: map[string]string{
+		"Host": "kv-host",
+		"Port": "9000",
+	}}
+
+	type Config struct {
+		Host     string `env:"HOST" default:"localhost"`
+		Port     int    `default:"8080"`
+		Required string `required:"true"`
+		Server   struct {
+			TLS struct {
+				Cert string
+			}
+		}
+	}
+
+	var cfg Config
+	parser := configlib.NewParser(
+		configlib.WithDisableAutoEnv(),
+		configlib.WithDisableAutoFlag(),
+		configlib.WithSources(secrets, kv),
+		configlib.WithEnvLookup(envLookup(map[string]string{"HOST": "env-host"})),
+	)
+	err := parser.ParseArgs(&cfg, nil)
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	// Env takes precedence over custom sources
+	if cfg.Host != "env-host" {
+		t.Errorf("Host = %s, want env-host", cfg.Host)
+	}
+	// Custom sources take precedence over defaults
+	if cfg.Port != 9000 {
+		t.Errorf("Port = %d, want 9000", cfg.Port)
+	}
+	if cfg.Required != "from-secrets" {
+		t.Errorf("Required = %s, want from-secrets", cfg.Required)
+	}
+	// Fields without env, flag or default are still offered to custom sources
+	if cfg.Server.TLS.Cert != "secret-cert" {
+		t.Errorf("Server.TLS.Cert = %s, want secret-cert", cfg.Server.TLS.Cert)
+	}
+}
+
+func TestWithSourcesOrder(t *testing.T) {
+	first := mapSource{name: "first", values: map[string]string{"Host": "first-host"}}
+	second := mapSource{name: "second", values: map[string]string{"Host": "second-host", "Port": "9000"}}
+
+	var cfg SimpleConfig
+	parser := configlib.NewParser(
+		configlib.WithSources(first, second),
+		configlib.WithEnvLookup(envLookup(map[string]string{"REQUIRED": "x"})),
+	)
+	err := parser.ParseArgs(&cfg, nil)
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if cfg.Host != "first-host" {
+		t.Errorf("Host = %s, want first-host", cfg.Host)
+	}
+	if cfg.Port != 9000 {
+		t.Errorf("Port = %d, want 9000", cfg.Port)
+	}
+}
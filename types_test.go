@@ -0,0 +1,1076 @@
+package configlib_test
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bherbruck/configlib"
+)
+
+type PointerConfig struct {
+	Retries *int           `env:"RETRIES" flag:"retries"`
+	Verbose *bool          `env:"VERBOSE" flag:"verbose"`
+	Name    *string        `env:"NAME" flag:"name" default:"app"`
+	Timeout *time.Duration `env:"TIMEOUT" flag:"timeout"`
+	TLS     *struct {
+		Cert string `env:"TLS_CERT" flag:"tls-cert"`
+		Key  string `env:"TLS_KEY" flag:"tls-key"`
+	}
+}
+
+func TestPointerFields(t *testing.T) {
+	t.Run("unset pointers stay nil", func(t *testing.T) {
+		var cfg PointerConfig
+		err := configlib.NewParser(configlib.WithEnvLookup(envLookup(nil))).ParseArgs(&cfg, nil)
+		if err != nil {
+			t.Fatalf("ParseArgs failed: %v", err)
+		}
+
+		if cfg.Retries != nil {
+			t.Errorf("Retries = %d, want nil", *cfg.Retries)
+		}
+		if cfg.Verbose != nil {
+			t.Errorf("Verbose = %v, want nil", *cfg.Verbose)
+		}
+		if cfg.Timeout != nil {
+			t.Errorf("Timeout = %v, want nil", *cfg.Timeout)
+		}
+		if cfg.TLS != nil {
+			t.Errorf("TLS = %+v, want nil", *cfg.TLS)
+		}
+		// Defaults still allocate
+		if cfg.Name == nil || *cfg.Name != "app" {
+			t.Errorf("Name = %v, want app", cfg.Name)
+		}
+	})
+
+	t.Run("zero values are distinguished from unset", func(t *testing.T) {
+		var cfg PointerConfig
+		parser := configlib.NewParser(configlib.WithEnvLookup(envLookup(map[string]string{
+			"TIMEOUT":  "0s",
+			"TLS_CERT": "/etc/cert.pem",
+		})))
+		err := parser.ParseArgs(&cfg, []string{"--retries", "0", "--verbose"})
+		if err != nil {
+			t.Fatalf("ParseArgs failed: %v", err)
+		}
+
+		if cfg.Retries == nil || *cfg.Retries != 0 {
+			t.Errorf("Retries = %v, want 0", cfg.Retries)
+		}
+		if cfg.Verbose == nil || !*cfg.Verbose {
+			t.Errorf("Verbose = %v, want true", cfg.Verbose)
+		}
+		if cfg.Timeout == nil || *cfg.Timeout != 0 {
+			t.Errorf("Timeout = %v, want 0s", cfg.Timeout)
+		}
+		if cfg.TLS == nil {
+			t.Fatal("TLS = nil, want allocated struct")
+		}
+		if cfg.TLS.Cert != "/etc/cert.pem" {
+			t.Errorf("TLS.Cert = %s, want /etc/cert.pem", cfg.TLS.Cert)
+		}
+	})
+
+	t.Run("existing struct pointers are reused", func(t *testing.T) {
+		var cfg PointerConfig
+		cfg.TLS = &struct {
+			Cert string `env:"TLS_CERT" flag:"tls-cert"`
+			Key  string `env:"TLS_KEY" flag:"tls-key"`
+		}{Key: "preset"}
+
+		err := configlib.NewParser(configlib.WithEnvLookup(envLookup(nil))).ParseArgs(&cfg, []string{"--tls-cert", "cert"})
+		if err != nil {
+			t.Fatalf("ParseArgs failed: %v", err)
+		}
+
+		if cfg.TLS.Cert != "cert" || cfg.TLS.Key != "preset" {
+			t.Errorf("TLS = %+v, want {Cert:cert Key:preset}", *cfg.TLS)
+		}
+	})
+
+	t.Run("required fields of nil struct pointers", func(t *testing.T) {
+		type Config struct {
+			Opt *struct {
+				Key  string `env:"OPT_KEY" required:"true"`
+				Mode string `env:"OPT_MODE"`
+				Cert string `env:"OPT_CERT" required_if:"Opt.Mode=tls"`
+			}
+		}
+
+		var cfg Config
+		if err := configlib.NewParser(configlib.WithEnviron(nil)).ParseArgs(&cfg, nil); err != nil {
+			t.Fatalf("ParseArgs failed: %v", err)
+		}
+		if cfg.Opt != nil {
+			t.Errorf("Opt = %+v, want nil", *cfg.Opt)
+		}
+
+		cfg = Config{}
+		err := configlib.NewParser(configlib.WithEnviron([]string{"OPT_MODE=tls"})).ParseArgs(&cfg, nil)
+		for _, want := range []string{"Opt.Key (env: OPT_KEY", "Opt.Cert (env: OPT_CERT"} {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, want)
+			}
+		}
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		var cfg PointerConfig
+		parser := configlib.NewParser(configlib.WithEnvLookup(envLookup(map[string]string{"RETRIES": "many"})))
+		err := parser.ParseArgs(&cfg, nil)
+		if err == nil {
+			t.Fatal("Expected error for invalid pointer value, got nil")
+		}
+		if cfg.Retries != nil {
+			t.Errorf("Retries = %d, want nil", *cfg.Retries)
+		}
+	})
+}
+
+type MapConfig struct {
+	Labels map[string]string  `env:"LABELS" flag:"label" default:"env=dev"`
+	Limits map[string]int     `env:"LIMITS" flag:"limit"`
+	Ratios map[string]float64 `env:"RATIOS" flag:"ratio"`
+}
+
+func TestMapFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		environ  []string
+		cliArgs  []string
+		expected MapConfig
+	}{
+		{
+			name: "defaults",
+			expected: MapConfig{
+				Labels: map[string]string{"env": "dev"},
+			},
+		},
+		{
+			name:    "env var",
+			environ: []string{"LABELS=team=infra, tier = gold", "LIMITS=acme=10,globex=20"},
+			expected: MapConfig{
+				Labels: map[string]string{"team": "infra", "tier": "gold"},
+				Limits: map[string]int{"acme": 10, "globex": 20},
+			},
+		},
+		{
+			name:    "per-key env vars",
+			environ: []string{"LABELS=team=infra", "LABELS_REGION=us-east", "LIMITS_acme=10"},
+			expected: MapConfig{
+				Labels: map[string]string{"team": "infra", "REGION": "us-east"},
+				Limits: map[string]int{"acme": 10},
+			},
+		},
+		{
+			name:    "repeated flags accumulate",
+			environ: []string{"LABELS=team=infra"},
+			cliArgs: []string{"--label", "a=1,b=2", "--label", "c=3", "--ratio", "x=0.5"},
+			expected: MapConfig{
+				Labels: map[string]string{"a": "1", "b": "2", "c": "3"},
+				Ratios: map[string]float64{"x": 0.5},
+			},
+		},
+		{
+			name:    "values may contain equals signs",
+			cliArgs: []string{"--label", "query=a=b"},
+			expected: MapConfig{
+				Labels: map[string]string{"query": "a=b"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg MapConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err != nil {
+				t.Fatalf("ParseArgs failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(cfg, tt.expected) {
+				t.Errorf("ParseArgs() got = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMapEnvSkipsOtherFields(t *testing.T) {
+	type Config struct {
+		Labels   map[string]string `env:"LABELS"`
+		LabelMax string            `env:"LABELS_MAX"`
+	}
+	expected := Config{
+		Labels:   map[string]string{"TEAM": "infra"},
+		LabelMax: "5",
+	}
+
+	t.Run("env", func(t *testing.T) {
+		var cfg Config
+		parser := configlib.NewParser(configlib.WithEnviron([]string{"LABELS_TEAM=infra", "LABELS_MAX=5"}))
+		if err := parser.ParseArgs(&cfg, nil); err != nil {
+			t.Fatalf("ParseArgs failed: %v", err)
+		}
+		if !reflect.DeepEqual(cfg, expected) {
+			t.Errorf("ParseArgs() got = %+v, want %+v", cfg, expected)
+		}
+	})
+
+	t.Run("dotenv", func(t *testing.T) {
+		path := writeConfigFile(t, ".env", "LABELS_TEAM=infra\nLABELS_MAX=5\n")
+		var cfg Config
+		parser := configlib.NewParser(configlib.WithDotEnv(path), configlib.WithEnviron(nil))
+		if err := parser.ParseArgs(&cfg, nil); err != nil {
+			t.Fatalf("ParseArgs failed: %v", err)
+		}
+		if !reflect.DeepEqual(cfg, expected) {
+			t.Errorf("ParseArgs() got = %+v, want %+v", cfg, expected)
+		}
+	})
+}
+
+func TestMapFieldErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+		errMsg  string
+	}{
+		{
+			name:    "flag entry without equals",
+			cliArgs: []string{"--label", "team"},
+			errMsg:  `invalid map entry "team": expected key=value`,
+		},
+		{
+			name:    "invalid env value",
+			environ: []string{"LIMITS=acme=lots"},
+			errMsg:  `error setting field Limits: invalid value for key "acme"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg MapConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestMapFieldFromConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+labels:
+  team: infra
+  tier: gold
+limits:
+  acme: 10
+`)
+
+	var cfg MapConfig
+	parser := configlib.NewParser(
+		configlib.WithConfigFile(path),
+		configlib.WithEnviron(nil),
+	)
+	err := parser.ParseArgs(&cfg, nil)
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	expected := MapConfig{
+		Labels: map[string]string{"team": "infra", "tier": "gold"},
+		Limits: map[string]int{"acme": 10},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("ParseArgs() got = %+v, want %+v", cfg, expected)
+	}
+}
+
+type TypedSliceConfig struct {
+	Ports     []int           `env:"PORTS" flag:"ports" default:"80,443"`
+	IDs       []uint64        `env:"IDS" flag:"ids"`
+	Weights   []float64       `env:"WEIGHTS" flag:"weights"`
+	Flags     []bool          `env:"FLAGS" flag:"flags"`
+	Intervals []time.Duration `env:"INTERVALS" flag:"intervals"`
+	Server    struct {
+		Backoff []time.Duration `env:"BACKOFF" flag:"backoff"`
+	}
+}
+
+func TestTypedSlices(t *testing.T) {
+	var cfg TypedSliceConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{
+		"IDS=1, 2,3",
+		"WEIGHTS=0.5,1.25",
+		"FLAGS=true,false,1",
+		"INTERVALS=1s,1m",
+	}))
+	err := parser.ParseArgs(&cfg, []string{"--backoff", "100ms,2s"})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	expected := TypedSliceConfig{
+		Ports:     []int{80, 443},
+		IDs:       []uint64{1, 2, 3},
+		Weights:   []float64{0.5, 1.25},
+		Flags:     []bool{true, false, true},
+		Intervals: []time.Duration{time.Second, time.Minute},
+	}
+	expected.Server.Backoff = []time.Duration{100 * time.Millisecond, 2 * time.Second}
+
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("ParseArgs() got = %+v, want %+v", cfg, expected)
+	}
+}
+
+func TestTypedSliceErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+		errMsg  string
+	}{
+		{
+			name:    "invalid env element",
+			environ: []string{"PORTS=80,http,443"},
+			errMsg:  `error setting field Ports: invalid element 1 "http"`,
+		},
+		{
+			name:    "invalid nested element",
+			environ: []string{"BACKOFF=1s,soon"},
+			errMsg:  `error setting field Server.Backoff: invalid element 1 "soon"`,
+		},
+		{
+			name:    "invalid flag element",
+			cliArgs: []string{"--weights", "0.5,heavy"},
+			errMsg:  `invalid element 1 "heavy"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg TypedSliceConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestSliceSeparatorAndQuoting(t *testing.T) {
+	type Config struct {
+		DSNs   []string          `env:"DSNS" flag:"dsn" sep:";"`
+		Hosts  []string          `env:"HOSTS" flag:"host"`
+		Paths  []string          `env:"PATHS" sep:"::"`
+		Labels map[string]string `env:"LABELS" sep:";"`
+	}
+
+	tests := []struct {
+		name     string
+		environ  []string
+		cliArgs  []string
+		expected Config
+	}{
+		{
+			name:    "custom separator",
+			environ: []string{"DSNS=host=a,port=1; host=b,port=2", "PATHS=/a::/b", "LABELS=a=1,2;b=3"},
+			expected: Config{
+				DSNs:   []string{"host=a,port=1", "host=b,port=2"},
+				Paths:  []string{"/a", "/b"},
+				Labels: map[string]string{"a": "1,2", "b": "3"},
+			},
+		},
+		{
+			name:    "quoted elements",
+			environ: []string{`HOSTS="a,b", 'c,"d"' , " e "`},
+			expected: Config{
+				Hosts: []string{"a,b", `c,"d"`, " e "},
+			},
+		},
+		{
+			name:    "escape sequences",
+			environ: []string{`HOSTS=a\,b,c\\,"d\"e\n"`},
+			expected: Config{
+				Hosts: []string{"a,b", `c\`, "d\"e\n"},
+			},
+		},
+		{
+			name:    "json array in env",
+			environ: []string{`HOSTS=["a,b", "c"]`, `DSNS=["x;y"]`},
+			expected: Config{
+				Hosts: []string{"a,b", "c"},
+				DSNs:  []string{"x;y"},
+			},
+		},
+		{
+			name:    "quoted flag values",
+			cliArgs: []string{"--host", `"a,b",c`, "--dsn", "x;y"},
+			expected: Config{
+				Hosts: []string{"a,b", "c"},
+				DSNs:  []string{"x", "y"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err != nil {
+				t.Fatalf("ParseArgs failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(cfg, tt.expected) {
+				t.Errorf("ParseArgs() got = %#v, want %#v", cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSliceUnterminatedQuote(t *testing.T) {
+	type Config struct {
+		Hosts []string `env:"HOSTS"`
+	}
+
+	var cfg Config
+	parser := configlib.NewParser(configlib.WithEnviron([]string{`HOSTS="a,b`}))
+	err := parser.ParseArgs(&cfg, nil)
+	if err == nil || !strings.Contains(err.Error(), "error setting field Hosts: unterminated quote") {
+		t.Errorf("ParseArgs() error = %v, want unterminated quote error", err)
+	}
+}
+
+func TestSliceElementsWithSeparatorFromConfigFile(t *testing.T) {
+	type Config struct {
+		Hosts  []string
+		Labels map[string]string
+	}
+
+	path := writeConfigFile(t, "config.json", `{"hosts": ["a,b", "c"], "labels": {"k": "x,y"}}`)
+
+	var cfg Config
+	parser := configlib.NewParser(configlib.WithConfigFile(path), configlib.WithEnviron(nil))
+	err := parser.ParseArgs(&cfg, nil)
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if !slicesEqual(cfg.Hosts, []string{"a,b", "c"}) {
+		t.Errorf("Hosts = %#v, want [a,b c]", cfg.Hosts)
+	}
+	if cfg.Labels["k"] != "x,y" {
+		t.Errorf("Labels = %#v, want map[k:x,y]", cfg.Labels)
+	}
+}
+
+// LogLevel implements encoding.TextUnmarshaler
+type LogLevel int
+
+func (l *LogLevel) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	case "warn":
+		*l = 2
+	case "error":
+		*l = 3
+	default:
+		return fmt.Errorf("unknown log level %q", text)
+	}
+	return nil
+}
+
+// TenantID implements flag.Value
+type TenantID struct {
+	Org  string
+	Name string
+}
+
+func (id *TenantID) String() string { return id.Org + "/" + id.Name }
+
+func (id *TenantID) Set(s string) error {
+	org, name, ok := strings.Cut(s, "/")
+	if !ok {
+		return fmt.Errorf("tenant ID %q must be org/name", s)
+	}
+	id.Org, id.Name = org, name
+	return nil
+}
+
+type CustomTypesConfig struct {
+	Level    LogLevel            `env:"LEVEL" flag:"level" default:"info"`
+	Tenant   TenantID            `env:"TENANT" flag:"tenant"`
+	Fallback *TenantID           `env:"FALLBACK" flag:"fallback"`
+	Levels   []LogLevel          `env:"LEVELS" flag:"levels"`
+	Owners   map[string]TenantID `env:"OWNERS" flag:"owner"`
+}
+
+func TestCustomTypes(t *testing.T) {
+	var cfg CustomTypesConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{
+		"TENANT=acme/web",
+		"LEVELS=debug,ERROR",
+		"OWNERS=api=acme/api",
+	}))
+	err := parser.ParseArgs(&cfg, []string{"--level", "warn"})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	expected := CustomTypesConfig{
+		Level:  2,
+		Tenant: TenantID{Org: "acme", Name: "web"},
+		Levels: []LogLevel{0, 3},
+		Owners: map[string]TenantID{"api": {Org: "acme", Name: "api"}},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("ParseArgs() got = %+v, want %+v", cfg, expected)
+	}
+}
+
+func TestCustomTypeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+		errMsg  string
+	}{
+		{
+			name:    "text unmarshaler from env",
+			environ: []string{"LEVEL=loud"},
+			errMsg:  `error setting field Level: unknown log level "loud"`,
+		},
+		{
+			name:    "text unmarshaler from flag",
+			cliArgs: []string{"--level", "loud"},
+			errMsg:  `invalid value "loud" for flag -level: unknown log level "loud"`,
+		},
+		{
+			name:    "flag value from flag",
+			cliArgs: []string{"--fallback", "acme"},
+			errMsg:  `invalid value "acme" for flag -fallback: tenant ID "acme" must be org/name`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg CustomTypesConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+// Point stands in for a third-party type without parsing methods
+type Point struct {
+	X, Y int
+}
+
+func decodePoint(s string) (any, error) {
+	var pt Point
+	if _, err := fmt.Sscanf(s, "%d:%d", &pt.X, &pt.Y); err != nil {
+		return nil, fmt.Errorf("invalid point %q", s)
+	}
+	return pt, nil
+}
+
+func TestWithDecoder(t *testing.T) {
+	type Config struct {
+		Origin  Point         `env:"ORIGIN" flag:"origin" default:"0:0"`
+		Target  *Point        `env:"TARGET" flag:"target"`
+		Path    []Point       `env:"PATH_POINTS" flag:"path"`
+		Timeout time.Duration `env:"TIMEOUT" flag:"timeout"`
+		Words   []string      `env:"WORDS" flag:"words,w"`
+	}
+
+	seconds := func(s string) (any, error) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		return time.Duration(n) * time.Second, nil
+	}
+
+	var cfg Config
+	parser := configlib.NewParser(
+		configlib.WithDecoder(reflect.TypeOf(Point{}), decodePoint),
+		configlib.WithDecoder(reflect.TypeOf(time.Duration(0)), seconds),
+		configlib.WithDecoder(reflect.TypeOf([]string(nil)), func(s string) (any, error) { return strings.Fields(s), nil }),
+		configlib.WithEnviron([]string{"PATH_POINTS=1:1,2:4", "TIMEOUT=30"}),
+	)
+	err := parser.ParseArgs(&cfg, []string{"--target", "3:4", "-w", "a b c"})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	expected := Config{
+		Origin:  Point{},
+		Target:  &Point{X: 3, Y: 4},
+		Path:    []Point{{1, 1}, {2, 4}},
+		Timeout: 30 * time.Second,
+		Words:   []string{"a", "b", "c"},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("ParseArgs() got = %+v, want %+v", cfg, expected)
+	}
+}
+
+func TestWithDecoderErrors(t *testing.T) {
+	type Config struct {
+		Origin Point `env:"ORIGIN" flag:"origin"`
+	}
+
+	tests := []struct {
+		name    string
+		decode  func(string) (any, error)
+		environ []string
+		cliArgs []string
+		errMsg  string
+	}{
+		{
+			name:    "decoder error from env",
+			decode:  decodePoint,
+			environ: []string{"ORIGIN=here"},
+			errMsg:  `error setting field Origin: invalid point "here"`,
+		},
+		{
+			name:    "decoder error from flag",
+			decode:  decodePoint,
+			cliArgs: []string{"--origin", "here"},
+			errMsg:  `invalid value "here" for flag -origin: invalid point "here"`,
+		},
+		{
+			name:    "decoder returns wrong type",
+			decode:  func(string) (any, error) { return "not a point", nil },
+			environ: []string{"ORIGIN=1:2"},
+			errMsg:  "decoder for configlib_test.Point returned string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			parser := configlib.NewParser(
+				configlib.WithDecoder(reflect.TypeOf(Point{}), tt.decode),
+				configlib.WithEnviron(tt.environ),
+			)
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+type NetworkConfig struct {
+	BindIP    net.IP           `env:"BIND_IP" flag:"bind-ip" default:"0.0.0.0"`
+	Listen    netip.Addr       `env:"LISTEN" flag:"listen"`
+	AddrPort  netip.AddrPort   `env:"ADDR_PORT" flag:"addr-port"`
+	Subnet    netip.Prefix     `env:"SUBNET" flag:"subnet"`
+	Allowlist []netip.Prefix   `env:"ALLOWLIST" flag:"allow"`
+	Resolvers []net.IP         `env:"RESOLVERS" flag:"resolver"`
+	Upstream  *url.URL         `env:"UPSTREAM" flag:"upstream"`
+	Mirrors   []*url.URL       `env:"MIRRORS" flag:"mirror"`
+	Homepage  url.URL          `env:"HOMEPAGE" flag:"homepage"`
+	MAC       net.HardwareAddr `env:"MAC" flag:"mac"`
+}
+
+func TestNetworkTypes(t *testing.T) {
+	var cfg NetworkConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{
+		"LISTEN=::1",
+		"ADDR_PORT=127.0.0.1:8080",
+		"SUBNET=10.0.0.0/8",
+		"RESOLVERS=1.1.1.1, 8.8.8.8",
+		"MIRRORS=https://a.example.com,https://b.example.com/path",
+		"HOMEPAGE=https://example.com",
+		"MAC=00:00:5e:00:53:01",
+	}))
+	err := parser.ParseArgs(&cfg, []string{
+		"--upstream", "https://api.example.com:8443/v1",
+		"--allow", "192.168.0.0/16", "--allow", "fd00::/8",
+	})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if !cfg.BindIP.Equal(net.IPv4zero) {
+		t.Errorf("BindIP = %v, want 0.0.0.0", cfg.BindIP)
+	}
+	if cfg.Listen != netip.IPv6Loopback() {
+		t.Errorf("Listen = %v, want ::1", cfg.Listen)
+	}
+	if cfg.AddrPort != netip.MustParseAddrPort("127.0.0.1:8080") {
+		t.Errorf("AddrPort = %v, want 127.0.0.1:8080", cfg.AddrPort)
+	}
+	if cfg.Subnet != netip.MustParsePrefix("10.0.0.0/8") {
+		t.Errorf("Subnet = %v, want 10.0.0.0/8", cfg.Subnet)
+	}
+	expectedAllowlist := []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16"), netip.MustParsePrefix("fd00::/8")}
+	if !reflect.DeepEqual(cfg.Allowlist, expectedAllowlist) {
+		t.Errorf("Allowlist = %v, want %v", cfg.Allowlist, expectedAllowlist)
+	}
+	if len(cfg.Resolvers) != 2 || !cfg.Resolvers[0].Equal(net.IPv4(1, 1, 1, 1)) || !cfg.Resolvers[1].Equal(net.IPv4(8, 8, 8, 8)) {
+		t.Errorf("Resolvers = %v, want [1.1.1.1 8.8.8.8]", cfg.Resolvers)
+	}
+	if cfg.Upstream == nil || cfg.Upstream.Host != "api.example.com:8443" || cfg.Upstream.Path != "/v1" {
+		t.Errorf("Upstream = %v, want https://api.example.com:8443/v1", cfg.Upstream)
+	}
+	if len(cfg.Mirrors) != 2 || cfg.Mirrors[1].String() != "https://b.example.com/path" {
+		t.Errorf("Mirrors = %v, want [https://a.example.com https://b.example.com/path]", cfg.Mirrors)
+	}
+	if cfg.Homepage.Host != "example.com" {
+		t.Errorf("Homepage = %v, want https://example.com", cfg.Homepage)
+	}
+	if cfg.MAC.String() != "00:00:5e:00:53:01" {
+		t.Errorf("MAC = %v, want 00:00:5e:00:53:01", cfg.MAC)
+	}
+}
+
+func TestNetworkTypesFromFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+	}{
+		{
+			name:    "flags",
+			cliArgs: []string{"--bind-ip", "10.0.0.1", "--mac", "00:11:22:33:44:55", "--resolver", "1.1.1.1", "--resolver", "8.8.8.8"},
+		},
+		{
+			name:    "json array env",
+			environ: []string{`RESOLVERS=["1.1.1.1", "8.8.8.8"]`},
+			cliArgs: []string{"--bind-ip=10.0.0.1", "--mac=00:11:22:33:44:55"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg NetworkConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			if err := parser.ParseArgs(&cfg, tt.cliArgs); err != nil {
+				t.Fatalf("ParseArgs failed: %v", err)
+			}
+
+			if !cfg.BindIP.Equal(net.IPv4(10, 0, 0, 1)) {
+				t.Errorf("BindIP = %v, want 10.0.0.1", cfg.BindIP)
+			}
+			if cfg.MAC.String() != "00:11:22:33:44:55" {
+				t.Errorf("MAC = %v, want 00:11:22:33:44:55", cfg.MAC)
+			}
+			if len(cfg.Resolvers) != 2 || !cfg.Resolvers[0].Equal(net.IPv4(1, 1, 1, 1)) || !cfg.Resolvers[1].Equal(net.IPv4(8, 8, 8, 8)) {
+				t.Errorf("Resolvers = %v, want [1.1.1.1 8.8.8.8]", cfg.Resolvers)
+			}
+		})
+	}
+}
+
+func TestNetworkTypeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+		errMsg  string
+	}{
+		{
+			name:    "invalid ip from env",
+			environ: []string{"BIND_IP=300.0.0.1"},
+			errMsg:  "error setting field BindIP",
+		},
+		{
+			name:    "invalid addr from flag",
+			cliArgs: []string{"--listen", "localhost"},
+			errMsg:  `invalid value "localhost" for flag -listen`,
+		},
+		{
+			name:    "invalid prefix element",
+			environ: []string{"ALLOWLIST=10.0.0.0/8,10.0.0.0/99"},
+			errMsg:  `error setting field Allowlist: invalid element 1 "10.0.0.0/99"`,
+		},
+		{
+			name:    "invalid url from flag",
+			cliArgs: []string{"--upstream", "://missing-scheme"},
+			errMsg:  `invalid value "://missing-scheme" for flag -upstream`,
+		},
+		{
+			name:    "invalid mac",
+			environ: []string{"MAC=00:00:5e"},
+			errMsg:  "error setting field MAC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg NetworkConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+type TimeConfig struct {
+	Start    time.Time      `env:"START" flag:"start"`
+	Cutoff   time.Time      `env:"CUTOFF" flag:"cutoff" layout:"DateOnly"`
+	Window   time.Time      `env:"WINDOW" flag:"window" layout:"2006-01-02 15:04"`
+	Holidays []time.Time    `env:"HOLIDAYS" flag:"holiday" layout:"DateOnly"`
+	Zone     *time.Location `env:"ZONE" flag:"zone" default:"UTC"`
+}
+
+func TestTimeFields(t *testing.T) {
+	var cfg TimeConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{
+		"START=2024-03-01T09:30:00+01:00",
+		"HOLIDAYS=2024-12-25,2024-12-26",
+		"ZONE=America/New_York",
+	}))
+	err := parser.ParseArgs(&cfg, []string{
+		"--cutoff", "2024-06-30",
+		"--window", "2024-07-01 02:00",
+	})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if want := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC); !cfg.Start.Equal(want) {
+		t.Errorf("Start = %v, want %v", cfg.Start, want)
+	}
+	if want := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC); !cfg.Cutoff.Equal(want) {
+		t.Errorf("Cutoff = %v, want %v", cfg.Cutoff, want)
+	}
+	if want := time.Date(2024, 7, 1, 2, 0, 0, 0, time.UTC); !cfg.Window.Equal(want) {
+		t.Errorf("Window = %v, want %v", cfg.Window, want)
+	}
+	if len(cfg.Holidays) != 2 || cfg.Holidays[1].Day() != 26 {
+		t.Errorf("Holidays = %v, want [2024-12-25 2024-12-26]", cfg.Holidays)
+	}
+	if cfg.Zone == nil || cfg.Zone.String() != "America/New_York" {
+		t.Errorf("Zone = %v, want America/New_York", cfg.Zone)
+	}
+}
+
+func TestTimeFieldsFromConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+start = 2024-03-01T09:30:00Z
+cutoff = 2024-06-30
+holidays = [2024-12-25, 2024-12-26]
+`)
+
+	var cfg TimeConfig
+	parser := configlib.NewParser(
+		configlib.WithConfigFile(path),
+		configlib.WithEnviron(nil),
+	)
+	err := parser.ParseArgs(&cfg, nil)
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if want := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC); !cfg.Start.Equal(want) {
+		t.Errorf("Start = %v, want %v", cfg.Start, want)
+	}
+	if want := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC); !cfg.Cutoff.Equal(want) {
+		t.Errorf("Cutoff = %v, want %v", cfg.Cutoff, want)
+	}
+	if len(cfg.Holidays) != 2 || cfg.Holidays[0].Day() != 25 {
+		t.Errorf("Holidays = %v, want [2024-12-25 2024-12-26]", cfg.Holidays)
+	}
+	if cfg.Zone != time.UTC {
+		t.Errorf("Zone = %v, want UTC", cfg.Zone)
+	}
+}
+
+func TestTimeFieldErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+		errMsg  string
+	}{
+		{
+			name:    "not rfc3339",
+			environ: []string{"START=2024-03-01"},
+			errMsg:  "error setting field Start",
+		},
+		{
+			name:    "layout mismatch from flag",
+			cliArgs: []string{"--cutoff", "30/06/2024"},
+			errMsg:  `invalid value "30/06/2024" for flag -cutoff`,
+		},
+		{
+			name:    "unknown time zone",
+			environ: []string{"ZONE=Mars/Olympus_Mons"},
+			errMsg:  "error setting field Zone: unknown time zone Mars/Olympus_Mons",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg TimeConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+type ByteSizeConfig struct {
+	Cache   configlib.ByteSize   `env:"CACHE" flag:"cache" default:"64MiB"`
+	Buffer  int                  `env:"BUFFER" flag:"buffer" unit:"bytes" default:"4KiB"`
+	Limit   *uint32              `env:"LIMIT" flag:"limit" unit:"bytes"`
+	Quotas  []int64              `env:"QUOTAS" flag:"quota" unit:"bytes"`
+	Uploads []configlib.ByteSize `env:"UPLOADS" flag:"upload"`
+}
+
+func TestByteSize(t *testing.T) {
+	var cfg ByteSizeConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{
+		"LIMIT=1.5GB",
+		"QUOTAS=10MB, 1 GiB, 512",
+		"UPLOADS=2k,1.25Mi",
+	}))
+	err := parser.ParseArgs(&cfg, []string{"--buffer", "32 kib"})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if cfg.Cache != 64*configlib.MiB {
+		t.Errorf("Cache = %d, want %d", cfg.Cache, 64*configlib.MiB)
+	}
+	if cfg.Buffer != 32*1024 {
+		t.Errorf("Buffer = %d, want %d", cfg.Buffer, 32*1024)
+	}
+	if cfg.Limit == nil || *cfg.Limit != 1500000000 {
+		t.Errorf("Limit = %v, want 1500000000", cfg.Limit)
+	}
+	if expected := []int64{10000000, 1 << 30, 512}; !reflect.DeepEqual(cfg.Quotas, expected) {
+		t.Errorf("Quotas = %v, want %v", cfg.Quotas, expected)
+	}
+	if expected := []configlib.ByteSize{2000, 1310720}; !reflect.DeepEqual(cfg.Uploads, expected) {
+		t.Errorf("Uploads = %v, want %v", cfg.Uploads, expected)
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	tests := []struct {
+		size     configlib.ByteSize
+		expected string
+	}{
+		{0, "0B"},
+		{512, "512B"},
+		{1024, "1KiB"},
+		{1536, "1.5KiB"},
+		{64 * configlib.MiB, "64MiB"},
+		{1500 * configlib.MB, "1.5GB"},
+		{2 * configlib.TB, "2TB"},
+		{1000001, "1000001B"},
+		{8 * configlib.EiB, "8EiB"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.size.String(); got != tt.expected {
+			t.Errorf("ByteSize(%d).String() = %q, want %q", uint64(tt.size), got, tt.expected)
+		}
+	}
+}
+
+func TestByteSizeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+		errMsg  string
+	}{
+		{
+			name:    "unknown suffix",
+			environ: []string{"CACHE=64XB"},
+			errMsg:  `error setting field Cache: invalid byte size "64XB"`,
+		},
+		{
+			name:    "negative size from flag",
+			cliArgs: []string{"--buffer", "-1KB"},
+			errMsg:  `invalid value "-1KB" for flag -buffer: invalid byte size "-1KB"`,
+		},
+		{
+			name:    "overflows field type",
+			environ: []string{"LIMIT=8GiB"},
+			errMsg:  `error setting field Limit: byte size "8GiB" out of range for uint32`,
+		},
+		{
+			name:    "invalid element",
+			environ: []string{"QUOTAS=1MB,lots"},
+			errMsg:  `error setting field Quotas: invalid element 1 "lots"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg ByteSizeConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestInvalidUnitTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		config any
+	}{
+		{
+			name: "unknown unit",
+			config: &struct {
+				Size int `unit:"bits"`
+			}{},
+		},
+		{
+			name: "non-integer field",
+			config: &struct {
+				Size string `unit:"bytes"`
+			}{},
+		},
+		{
+			name: "decoded type",
+			config: &struct {
+				Size time.Duration `unit:"bytes"`
+			}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := configlib.NewParser(configlib.WithEnviron(nil))
+			err := parser.ParseArgs(tt.config, nil)
+			if err == nil || !strings.Contains(err.Error(), "invalid unit tag") {
+				t.Errorf("ParseArgs() error = %v, want invalid unit tag error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package configlib
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validator is implemented by config structs, at the root or nested, that
+// check rules spanning several fields. Validate is called after all values
+// are applied, on nested structs before the structs containing them.
+type Validator interface {
+	Validate() error
+}
+
+// parseRules converts the validation tags of field, checking that they
+// apply to its type. Allowed values and bounds are parsed like values of the
+// field, so they may use units such as durations or byte sizes.
+func (p *Parser) parseRules(field *fieldInfo) error {
+	typ := p.valueType(field.Type)
+	isList := !p.isLeafType(typ) && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Map)
+	elemType := typ
+	if isList {
+		elemType = typ.Elem()
+	}
+
+	for _, allowed := range field.OneOf {
+		val := reflect.New(elemType).Elem()
+		if err := p.setValue(val, allowed, field.Field); err != nil {
+			return fmt.Errorf("invalid oneof tag %q on field %s: %v", allowed, field.FieldPath, err)
+		}
+		field.oneOf = append(field.oneOf, val)
+	}
+
+	bounds := []struct {
+		name string
+		tag  string
+		dst  *reflect.Value
+	}{
+		{"min", field.Min, &field.min},
+		{"max", field.Max, &field.max},
+	}
+	for _, bound := range bounds {
+		if bound.tag == "" {
+			continue
+		}
+		if !isNumberKind(elemType.Kind()) {
+			return fmt.Errorf("invalid %s tag on field %s: %s is not a number", bound.name, field.FieldPath, elemType)
+		}
+		val := reflect.New(elemType).Elem()
+		if err := p.setValue(val, bound.tag, field.Field); err != nil {
+			return fmt.Errorf("invalid %s tag %q on field %s: %v", bound.name, bound.tag, field.FieldPath, err)
+		}
+		*bound.dst = val
+	}
+
+	lengths := []struct {
+		name string
+		tag  string
+		dst  *int
+	}{
+		{"minlen", field.MinLen, &field.minLen},
+		{"maxlen", field.MaxLen, &field.maxLen},
+	}
+	for _, length := range lengths {
+		*length.dst = -1
+		if length.tag == "" {
+			continue
+		}
+		if typ.Kind() != reflect.String && !isList {
+			return fmt.Errorf("invalid %s tag on field %s: %s has no length", length.name, field.FieldPath, typ)
+		}
+		n, err := strconv.Atoi(length.tag)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s tag %q on field %s: must be a non-negative integer", length.name, length.tag, field.FieldPath)
+		}
+		*length.dst = n
+	}
+
+	if field.Pattern != "" {
+		if elemType.Kind() != reflect.String {
+			return fmt.Errorf("invalid pattern tag on field %s: %s is not a string", field.FieldPath, elemType)
+		}
+		if _, err := regexp.Compile(field.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern tag %q on field %s: %v", field.Pattern, field.FieldPath, err)
+		}
+		// The pattern must match the whole value
+		field.pattern = regexp.MustCompile("^(?:" + field.Pattern + ")$")
+	}
+
+	return nil
+}
+
+// validateField checks the value applied to field against its validation
+// tags, returning a ValidationError for each problem found. Slices are
+// checked element by element and maps value by value. Values of secret
+// fields are redacted from the errors.
+func (p *Parser) validateField(field fieldInfo, value string, elems []string) []error {
+	quote := strconv.Quote
+	if field.Secret {
+		quote = func(string) string { return redacted }
+	}
+
+	var names, values []string
+	typ := p.valueType(field.Type)
+	elemType := typ
+	length := utf8.RuneCountInString(value)
+	switch {
+	case p.isLeafType(typ):
+		names, values = []string{quote(value)}, []string{value}
+	case typ.Kind() == reflect.Slice:
+		if elems == nil {
+			elems, _ = splitList(value, field.Sep)
+		}
+		for i, elem := range elems {
+			names = append(names, fmt.Sprintf("element %d %s", i, quote(elem)))
+		}
+		values, elemType, length = elems, typ.Elem(), len(elems)
+	case typ.Kind() == reflect.Map:
+		entries, _ := splitMapEntries(value, field.Sep)
+		for _, entry := range entries {
+			names = append(names, fmt.Sprintf("key %q value %s", entry[0], quote(entry[1])))
+			values = append(values, entry[1])
+		}
+		elemType, length = typ.Elem(), len(entries)
+	default:
+		names, values = []string{quote(value)}, []string{value}
+	}
+
+	var problems []error
+	report := func(val, format string, args ...any) {
+		if field.Secret {
+			val = redacted
+		}
+		problems = append(problems, &ValidationError{
+			FieldPath: field.FieldPath,
+			Value:     val,
+			Err:       fmt.Errorf(format, args...),
+		})
+	}
+
+	if field.minLen >= 0 && length < field.minLen {
+		report(value, "length %d is less than minlen %d", length, field.minLen)
+	}
+	if field.maxLen >= 0 && length > field.maxLen {
+		report(value, "length %d is greater than maxlen %d", length, field.maxLen)
+	}
+
+	for i, val := range values {
+		if field.pattern != nil && !field.pattern.MatchString(val) {
+			report(val, "%s does not match pattern %s", names[i], field.Pattern)
+		}
+
+		if len(field.oneOf) == 0 && !field.min.IsValid() && !field.max.IsValid() {
+			continue
+		}
+		// Values were already converted when applied, so this cannot fail
+		converted := reflect.New(elemType).Elem()
+		if err := p.setValue(converted, val, field.Field); err != nil {
+			continue
+		}
+		// Compare converted values, so that e.g. 60s matches oneof:"1m"
+		if len(field.oneOf) > 0 && !slices.ContainsFunc(field.oneOf, func(allowed reflect.Value) bool {
+			return reflect.DeepEqual(converted.Interface(), allowed.Interface())
+		}) {
+			report(val, "%s is not one of %s", names[i], strings.Join(field.OneOf, ", "))
+		}
+		if field.min.IsValid() && compareNumbers(converted, field.min) < 0 {
+			report(val, "%s is less than min %s", names[i], field.Min)
+		}
+		if field.max.IsValid() && compareNumbers(converted, field.max) > 0 {
+			report(val, "%s is greater than max %s", names[i], field.Max)
+		}
+	}
+
+	return problems
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// compareNumbers compares two values of the same numeric kind
+func compareNumbers(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint())
+	default:
+		return cmp.Compare(a.Float(), b.Float())
+	}
+}
+
+// validateStructs calls Validate on each walked struct implementing Validator,
+// innermost first, skipping structs behind pointers that were left nil. Errors
+// joined with errors.Join are wrapped in a StructError each.
+func (p *Parser) validateStructs() []error {
+	var problems []error
+	for _, s := range p.structs {
+		if leftNil(s.allocs) {
+			continue
+		}
+		v, ok := s.value.Addr().Interface().(Validator)
+		if !ok {
+			continue
+		}
+		err := v.Validate()
+		if err == nil {
+			continue
+		}
+
+		errs := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			errs = joined.Unwrap()
+		}
+		for _, err := range errs {
+			problems = append(problems, &StructError{StructPath: s.path, Err: err})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,572 @@
+package configlib_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bherbruck/configlib"
+)
+
+type OneOfConfig struct {
+	Level  string            `env:"LEVEL" flag:"level" default:"info" oneof:"debug,info,warn,error" desc:"Log level"`
+	Mode   *string           `env:"MODE" flag:"mode" oneof:"tcp, udp"`
+	Codecs []string          `env:"CODECS" flag:"codec" oneof:"gzip,zstd,br"`
+	Routes map[string]string `env:"ROUTES" flag:"route" oneof:"primary,replica"`
+	Shards int               `env:"SHARDS" flag:"shards" oneof:"1,2,4,8"`
+}
+
+func TestOneOf(t *testing.T) {
+	var cfg OneOfConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{
+		"MODE=udp",
+		"CODECS=zstd,gzip",
+		"ROUTES=reads=replica,writes=primary",
+	}))
+	err := parser.ParseArgs(&cfg, []string{"--shards", "4"})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if cfg.Level != "info" {
+		t.Errorf("Level = %q, want %q", cfg.Level, "info")
+	}
+	if cfg.Mode == nil || *cfg.Mode != "udp" {
+		t.Errorf("Mode = %v, want udp", cfg.Mode)
+	}
+	if expected := []string{"zstd", "gzip"}; !reflect.DeepEqual(cfg.Codecs, expected) {
+		t.Errorf("Codecs = %v, want %v", cfg.Codecs, expected)
+	}
+	if cfg.Shards != 4 {
+		t.Errorf("Shards = %d, want 4", cfg.Shards)
+	}
+}
+
+func TestOneOfErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+		errMsgs []string
+	}{
+		{
+			name:    "invalid flag value",
+			cliArgs: []string{"--level", "trace"},
+			errMsgs: []string{`Level: "trace" is not one of debug, info, warn, error`},
+		},
+		{
+			name:    "invalid env value",
+			environ: []string{"MODE=quic"},
+			errMsgs: []string{`Mode: "quic" is not one of tcp, udp`},
+		},
+		{
+			name:    "invalid slice element",
+			environ: []string{"CODECS=gzip,lz4"},
+			errMsgs: []string{`Codecs: element 1 "lz4" is not one of gzip, zstd, br`},
+		},
+		{
+			name:    "invalid map value",
+			cliArgs: []string{"--route", "reads=standby"},
+			errMsgs: []string{`Routes: key "reads" value "standby" is not one of primary, replica`},
+		},
+		{
+			name:    "all invalid fields reported",
+			environ: []string{"LEVEL=verbose", "SHARDS=3"},
+			errMsgs: []string{
+				"invalid fields:",
+				`Level: "verbose" is not one of debug, info, warn, error`,
+				`Shards: "3" is not one of 1, 2, 4, 8`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg OneOfConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil {
+				t.Fatal("ParseArgs() expected error, got nil")
+			}
+			for _, errMsg := range tt.errMsgs {
+				if !strings.Contains(err.Error(), errMsg) {
+					t.Errorf("ParseArgs() error = %v, want error containing %q", err, errMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestOneOfWithMissingRequiredFields(t *testing.T) {
+	var cfg struct {
+		Level string `env:"LEVEL" oneof:"debug,info"`
+		Token string `env:"TOKEN" required:"true"`
+	}
+	parser := configlib.NewParser(configlib.WithEnviron([]string{"LEVEL=trace"}))
+	err := parser.ParseArgs(&cfg, nil)
+	if err == nil {
+		t.Fatal("ParseArgs() expected error, got nil")
+	}
+
+	expected := "missing required fields:\n  - Token (env: TOKEN, flag: --token)\n" +
+		"invalid fields:\n  - Level: \"trace\" is not one of debug, info"
+	if err.Error() != expected {
+		t.Errorf("ParseArgs() error = %q, want %q", err.Error(), expected)
+	}
+}
+
+func TestOneOfConvertedValues(t *testing.T) {
+	var cfg struct {
+		Shards   int             `env:"SHARDS" oneof:"1,2,4"`
+		Interval time.Duration   `env:"INTERVAL" oneof:"1m,5m"`
+		Enabled  bool            `env:"ENABLED" oneof:"true"`
+		Weights  map[string]uint `env:"WEIGHTS" oneof:"10,20"`
+	}
+	parser := configlib.NewParser(configlib.WithEnviron([]string{
+		"SHARDS=01",
+		"INTERVAL=60s",
+		"ENABLED=1",
+		"WEIGHTS=a=010,b=20",
+	}))
+	if err := parser.ParseArgs(&cfg, nil); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	parser = configlib.NewParser(configlib.WithEnviron([]string{"INTERVAL=90s"}))
+	err := parser.ParseArgs(&cfg, nil)
+	want := `Interval: "90s" is not one of 1m, 5m`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("ParseArgs() error = %v, want error containing %q", err, want)
+	}
+}
+
+func TestInvalidOneOfTag(t *testing.T) {
+	var cfg struct {
+		Shards int `oneof:"1,two"`
+	}
+	parser := configlib.NewParser(configlib.WithEnviron(nil))
+	err := parser.ParseArgs(&cfg, nil)
+	want := `invalid oneof tag "two" on field Shards`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("ParseArgs() error = %v, want error containing %q", err, want)
+	}
+}
+
+type RangeConfig struct {
+	Port     int               `env:"PORT" flag:"port" default:"8080" min:"1" max:"65535"`
+	Ratio    float64           `env:"RATIO" flag:"ratio" min:"0" max:"1"`
+	Retries  *uint             `env:"RETRIES" flag:"retries" max:"10"`
+	Timeout  time.Duration     `env:"TIMEOUT" flag:"timeout" default:"30s" min:"1s" max:"5m"`
+	Cache    int64             `env:"CACHE" flag:"cache" unit:"bytes" max:"1GiB"`
+	Name     string            `env:"NAME" flag:"name" minlen:"3" maxlen:"8"`
+	Replicas []int             `env:"REPLICAS" flag:"replica" min:"1" minlen:"1" maxlen:"3"`
+	Weights  map[string]uint16 `env:"WEIGHTS" flag:"weight" max:"100"`
+}
+
+func TestRangeAndLength(t *testing.T) {
+	var cfg RangeConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{
+		"RATIO=0.25",
+		"RETRIES=0",
+		"CACHE=512MiB",
+		"NAME=héllo",
+		"REPLICAS=1,2,3",
+		"WEIGHTS=a=100,b=0",
+	}))
+	err := parser.ParseArgs(&cfg, []string{"--timeout", "5m"})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if cfg.Port != 8080 || cfg.Ratio != 0.25 || cfg.Timeout != 5*time.Minute || cfg.Name != "héllo" {
+		t.Errorf("ParseArgs() got = %+v", cfg)
+	}
+	if cfg.Retries == nil || *cfg.Retries != 0 {
+		t.Errorf("Retries = %v, want 0", cfg.Retries)
+	}
+}
+
+func TestRangeAndLengthErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+		errMsgs []string
+	}{
+		{
+			name:    "int above max",
+			cliArgs: []string{"--port", "70000"},
+			errMsgs: []string{`Port: "70000" is greater than max 65535`},
+		},
+		{
+			name:    "int below min",
+			environ: []string{"PORT=0"},
+			errMsgs: []string{`Port: "0" is less than min 1`},
+		},
+		{
+			name:    "float out of range",
+			environ: []string{"RATIO=1.5"},
+			errMsgs: []string{`Ratio: "1.5" is greater than max 1`},
+		},
+		{
+			name:    "duration below min",
+			cliArgs: []string{"--timeout", "500ms"},
+			errMsgs: []string{`Timeout: "500ms" is less than min 1s`},
+		},
+		{
+			name:    "byte size above max",
+			environ: []string{"CACHE=2GiB"},
+			errMsgs: []string{`Cache: "2GiB" is greater than max 1GiB`},
+		},
+		{
+			name:    "string too short",
+			environ: []string{"NAME=ab"},
+			errMsgs: []string{"Name: length 2 is less than minlen 3"},
+		},
+		{
+			name:    "slice too long with invalid element",
+			cliArgs: []string{"--replica", "1,0,2,3"},
+			errMsgs: []string{
+				"Replicas: length 4 is greater than maxlen 3",
+				`Replicas: element 1 "0" is less than min 1`,
+			},
+		},
+		{
+			name:    "map value above max",
+			environ: []string{"WEIGHTS=a=50,b=101"},
+			errMsgs: []string{`Weights: key "b" value "101" is greater than max 100`},
+		},
+		{
+			name:    "all invalid fields reported",
+			environ: []string{"PORT=0", "RETRIES=11", "NAME=toolongname"},
+			errMsgs: []string{
+				"invalid fields:",
+				`Port: "0" is less than min 1`,
+				`Retries: "11" is greater than max 10`,
+				"Name: length 11 is greater than maxlen 8",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg RangeConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil {
+				t.Fatal("ParseArgs() expected error, got nil")
+			}
+			for _, errMsg := range tt.errMsgs {
+				if !strings.Contains(err.Error(), errMsg) {
+					t.Errorf("ParseArgs() error = %v, want error containing %q", err, errMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidRangeTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		config any
+		errMsg string
+	}{
+		{
+			name: "min on string",
+			config: &struct {
+				Name string `min:"1"`
+			}{},
+			errMsg: "invalid min tag on field Name: string is not a number",
+		},
+		{
+			name: "unparsable max",
+			config: &struct {
+				Port int `max:"lots"`
+			}{},
+			errMsg: `invalid max tag "lots" on field Port`,
+		},
+		{
+			name: "minlen on int",
+			config: &struct {
+				Port int `minlen:"1"`
+			}{},
+			errMsg: "invalid minlen tag on field Port: int has no length",
+		},
+		{
+			name: "negative maxlen",
+			config: &struct {
+				Tags []string `maxlen:"-1"`
+			}{},
+			errMsg: `invalid maxlen tag "-1" on field Tags: must be a non-negative integer`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := configlib.NewParser(configlib.WithEnviron(nil))
+			err := parser.ParseArgs(tt.config, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+type PatternConfig struct {
+	Bucket  string            `env:"BUCKET" flag:"bucket" pattern:"[a-z0-9][a-z0-9.-]{2,62}"`
+	Region  *string           `env:"REGION" flag:"region" pattern:"[a-z]{2}-[a-z]+-\\d"`
+	Tenants []string          `env:"TENANTS" flag:"tenant" pattern:"[a-z][a-z0-9-]*"`
+	Owners  map[string]string `env:"OWNERS" flag:"owner" pattern:"@[a-z]+"`
+}
+
+func TestPattern(t *testing.T) {
+	var cfg PatternConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{
+		"BUCKET=logs.example-1",
+		"REGION=us-east-1",
+		"TENANTS=acme,globex-2",
+		"OWNERS=infra=@ops",
+	}))
+	if err := parser.ParseArgs(&cfg, nil); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if cfg.Bucket != "logs.example-1" || cfg.Region == nil || *cfg.Region != "us-east-1" {
+		t.Errorf("ParseArgs() got = %+v", cfg)
+	}
+}
+
+func TestPatternErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		cliArgs []string
+		errMsgs []string
+	}{
+		{
+			name:    "partial match is rejected",
+			cliArgs: []string{"--bucket", "Logs"},
+			errMsgs: []string{`Bucket: "Logs" does not match pattern [a-z0-9][a-z0-9.-]{2,62}`},
+		},
+		{
+			name:    "pointer field",
+			environ: []string{"REGION=us-east-1a"},
+			errMsgs: []string{`Region: "us-east-1a" does not match pattern [a-z]{2}-[a-z]+-\d`},
+		},
+		{
+			name:    "slice elements",
+			cliArgs: []string{"--tenant", "acme", "--tenant", "Globex,1nitech"},
+			errMsgs: []string{
+				`Tenants: element 1 "Globex" does not match pattern [a-z][a-z0-9-]*`,
+				`Tenants: element 2 "1nitech" does not match pattern [a-z][a-z0-9-]*`,
+			},
+		},
+		{
+			name:    "map values",
+			environ: []string{"OWNERS=infra=ops"},
+			errMsgs: []string{`Owners: key "infra" value "ops" does not match pattern @[a-z]+`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg PatternConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, tt.cliArgs)
+			if err == nil {
+				t.Fatal("ParseArgs() expected error, got nil")
+			}
+			for _, errMsg := range tt.errMsgs {
+				if !strings.Contains(err.Error(), errMsg) {
+					t.Errorf("ParseArgs() error = %v, want error containing %q", err, errMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidPatternTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		config any
+		errMsg string
+	}{
+		{
+			name: "invalid regexp",
+			config: &struct {
+				Slug string `pattern:"[a-z"`
+			}{},
+			errMsg: `invalid pattern tag "[a-z" on field Slug: error parsing regexp`,
+		},
+		{
+			name: "non-string field",
+			config: &struct {
+				Port int `pattern:"\\d+"`
+			}{},
+			errMsg: "invalid pattern tag on field Port: int is not a string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := configlib.NewParser(configlib.WithEnviron(nil))
+			err := parser.ParseArgs(tt.config, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseArgs() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+type WorkerPool struct {
+	MinWorkers int `env:"MIN_WORKERS" default:"1"`
+	MaxWorkers int `env:"MAX_WORKERS" default:"4"`
+}
+
+func (w WorkerPool) Validate() error {
+	if w.MinWorkers > w.MaxWorkers {
+		return fmt.Errorf("MinWorkers (%d) must not exceed MaxWorkers (%d)", w.MinWorkers, w.MaxWorkers)
+	}
+	return nil
+}
+
+type TLSSettings struct {
+	CertFile string `env:"TLS_CERT_FILE"`
+	KeyFile  string `env:"TLS_KEY_FILE"`
+}
+
+func (t *TLSSettings) Validate() error {
+	var errs []error
+	if t.CertFile != "" && t.KeyFile == "" {
+		errs = append(errs, errors.New("KeyFile is required with CertFile"))
+	}
+	if t.KeyFile != "" && t.CertFile == "" {
+		errs = append(errs, errors.New("CertFile is required with KeyFile"))
+	}
+	return errors.Join(errs...)
+}
+
+type ValidatorConfig struct {
+	Mode    string       `env:"MODE" default:"plain"`
+	Workers WorkerPool   `env:"-"`
+	TLS     *TLSSettings `env:"-"`
+	Port    int          `env:"PORT" default:"80" max:"65535"`
+}
+
+func (c *ValidatorConfig) Validate() error {
+	if c.Mode == "tls" && c.TLS == nil {
+		return errors.New("TLS settings are required in tls mode")
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	var cfg ValidatorConfig
+	parser := configlib.NewParser(configlib.WithEnviron([]string{
+		"MODE=tls",
+		"TLS_CERT_FILE=cert.pem",
+		"TLS_KEY_FILE=key.pem",
+	}))
+	if err := parser.ParseArgs(&cfg, nil); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if cfg.TLS == nil || cfg.TLS.KeyFile != "key.pem" {
+		t.Errorf("TLS = %+v, want key.pem", cfg.TLS)
+	}
+}
+
+func TestValidatorErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		errMsgs []string
+	}{
+		{
+			name:    "root validator",
+			environ: []string{"MODE=tls"},
+			errMsgs: []string{"validation errors:\n  - TLS settings are required in tls mode"},
+		},
+		{
+			name:    "nested value receiver",
+			environ: []string{"MIN_WORKERS=8"},
+			errMsgs: []string{"Workers: MinWorkers (8) must not exceed MaxWorkers (4)"},
+		},
+		{
+			name:    "joined errors reported separately",
+			environ: []string{"TLS_KEY_FILE=key.pem", "MIN_WORKERS=5"},
+			errMsgs: []string{
+				"validation errors:\n  - Workers: MinWorkers (5) must not exceed MaxWorkers (4)\n  - TLS: CertFile is required with KeyFile",
+			},
+		},
+		{
+			name:    "folded into report with invalid fields",
+			environ: []string{"PORT=70000", "MODE=tls"},
+			errMsgs: []string{
+				"invalid fields:\n  - Port: \"70000\" is greater than max 65535\n" +
+					"validation errors:\n  - TLS settings are required in tls mode",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg ValidatorConfig
+			parser := configlib.NewParser(configlib.WithEnviron(tt.environ))
+			err := parser.ParseArgs(&cfg, nil)
+			if err == nil {
+				t.Fatal("ParseArgs() expected error, got nil")
+			}
+			for _, errMsg := range tt.errMsgs {
+				if !strings.Contains(err.Error(), errMsg) {
+					t.Errorf("ParseArgs() error = %v, want error containing %q", err, errMsg)
+				}
+			}
+		})
+	}
+}
+
+type orderedValidator struct {
+	name  string
+	order *[]string
+}
+
+func (v *orderedValidator) Validate() error {
+	*v.order = append(*v.order, v.name)
+	return nil
+}
+
+func TestValidatorOrder(t *testing.T) {
+	type Inner struct {
+		orderedValidator
+		Value string `env:"INNER_VALUE"`
+	}
+	type Outer struct {
+		orderedValidator
+		Inner Inner
+	}
+	type Config struct {
+		orderedValidator
+		Outer Outer
+	}
+
+	var order []string
+	cfg := Config{
+		orderedValidator: orderedValidator{name: "root", order: &order},
+		Outer: Outer{
+			orderedValidator: orderedValidator{name: "outer", order: &order},
+			Inner:            Inner{orderedValidator: orderedValidator{name: "inner", order: &order}},
+		},
+	}
+	parser := configlib.NewParser(configlib.WithEnviron(nil))
+	if err := parser.ParseArgs(&cfg, nil); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if expected := []string{"inner", "outer", "root"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("Validate order = %v, want %v", order, expected)
+	}
+}